@@ -0,0 +1,485 @@
+package tsc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Default configs of calibration.
+// See tools/calibrate for details.
+const (
+	samples                 = 128
+	sampleDuration          = 16 * time.Millisecond
+	getClosestTSCSysRetries = 256
+)
+
+// Configs of sample quality.
+const (
+	// sampleRetries is the max number of retries for a closest pair whose bracket is too wide.
+	sampleRetries = 3
+	// wideBracketFactor decides a bracket is too wide if it's wider than wideBracketFactor × the narrowest one.
+	wideBracketFactor = 4
+)
+
+var (
+	// ErrUnsupported is returned when the hardware counter is unsupported.
+	ErrUnsupported = errors.New("tsc: hardware counter is unsupported")
+	// ErrInvalidOptions is returned when calibration options are invalid.
+	ErrInvalidOptions = errors.New("tsc: invalid calibration options")
+)
+
+// CalibrationOptions are options of CalibrateWithOptions.
+// Zero value means default for each field.
+//
+// The defaults take about 2s, e.g. {Samples: 6} takes about 100ms for latency-sensitive services,
+// and {Samples: 320, Interval: 32 * time.Millisecond} takes about 10s for a thorough one.
+type CalibrationOptions struct {
+	// Samples is the number of sample pairs, the samples of a pair are taken around a sleep of Interval.
+	// 128 by default.
+	Samples int
+	// Interval is the sleep between the samples of a pair.
+	// 16ms by default.
+	Interval time.Duration
+	// Retries is the number of reference clock reads for finding the closest pair of a sample, at least 2.
+	// 256 by default.
+	Retries int
+	// Estimator estimates coeff & offset from samples.
+	// OLS by default, use Robust if samples may be disturbed by preemptions or SMIs.
+	Estimator Estimator
+	// Reference returns the reference clock in nanoseconds, it's read Retries times for each sample.
+	// time.Now().UnixNano() by default.
+	Reference func() int64
+	// Timeout bounds the whole calibration, 0 means no timeout.
+	// It must be longer than Samples × Interval.
+	Timeout time.Duration
+	// RaisePriority raises the scheduling priority of the sampling thread if it's permitted
+	// (Linux only, it needs CAP_SYS_NICE).
+	RaisePriority bool
+	// SkipChangeCheck skips checking the change of coeff versus the last checked calibration,
+	// e.g. for recovering from a disturbed calibration. Other sanity checks are still applied.
+	SkipChangeCheck bool
+}
+
+// withDefaults validates options, and fills default values.
+func (o CalibrationOptions) withDefaults() (CalibrationOptions, error) {
+	switch {
+	case o.Samples < 0:
+		return o, fmt.Errorf("%w: negative Samples %d", ErrInvalidOptions, o.Samples)
+	case o.Interval < 0:
+		return o, fmt.Errorf("%w: negative Interval %s", ErrInvalidOptions, o.Interval)
+	case o.Retries < 0:
+		return o, fmt.Errorf("%w: negative Retries %d", ErrInvalidOptions, o.Retries)
+	case o.Retries == 1:
+		// A pair needs the counter read around two reference clock reads at least.
+		return o, fmt.Errorf("%w: Retries %d is less than 2", ErrInvalidOptions, o.Retries)
+	case o.Timeout < 0:
+		return o, fmt.Errorf("%w: negative Timeout %s", ErrInvalidOptions, o.Timeout)
+	}
+
+	if o.Samples == 0 {
+		o.Samples = samples
+	}
+
+	if o.Interval == 0 {
+		o.Interval = sampleDuration
+	}
+
+	if o.Retries == 0 {
+		o.Retries = getClosestTSCSysRetries
+	}
+
+	if o.Estimator == nil {
+		o.Estimator = OLS{}
+	}
+
+	if o.Reference == nil {
+		o.Reference = sysClock
+	}
+
+	if o.Timeout > 0 && o.Timeout <= time.Duration(o.Samples)*o.Interval {
+		return o, fmt.Errorf("%w: Timeout %s is too short for %d samples × Interval %s",
+			ErrInvalidOptions, o.Timeout, o.Samples, o.Interval)
+	}
+
+	return o, nil
+}
+
+// CalibrationResult is the result of a calibration.
+type CalibrationResult struct {
+	Coeff     float64
+	Offset    int64
+	Frequency float64 // Counter frequency in Hz.
+	Error     float64 // Estimated error (root-mean-square of residuals) in nanoseconds.
+	Samples   int     // Number of samples used in estimating.
+	Rejected  int     // Number of samples rejected as outliers by Estimator.
+	Dropped   int     // Number of samples dropped for too wide brackets (or throttling) before estimating.
+	Throttled int     // Number of samples dropped for cgroup CPU throttling.
+	Counter   int64   // Counter value at the middle of samples, where Coeff is measured.
+}
+
+// Calibrate calibrates counter & wall clock.
+//
+// It's a good practice that runs Calibrate periodically (e.g., 5 min is a good start),
+// because the wall clock may be adjusted (e.g. NTP).
+//
+// If the counter is unsupported do nothing.
+func Calibrate() {
+	_, _ = CalibrateWithOptions(CalibrationOptions{})
+}
+
+// CalibrateContext is Calibrate which stops sampling promptly when ctx is done.
+//
+// Returns ctx.Err() if ctx is done before publishing, the previous published parameters are kept then.
+// Other errors are the same as CalibrateWithOptions.
+func CalibrateContext(ctx context.Context) (CalibrationResult, error) {
+	return calibrate(ctx, CalibrationOptions{})
+}
+
+// CalibrateWithOptions calibrates counter & wall clock with options,
+// and publishes the result for UnixNano.
+//
+// Samples are taken on a locked OS thread pinned to one CPU (Linux only),
+// samples whose closest-pair bracket is too wide or which are throttled by cgroup CPU quota are retried,
+// and dropped if they are still too wide (or throttled),
+// then the rest are weighted by their uncertainties in estimating (if Estimator supports).
+//
+// Returns ErrInvalidOptions if options are invalid, ErrUnsupported if the counter is unsupported,
+// context.DeadlineExceeded if Timeout is exceeded, ErrTooFewSamples if less than half of samples are good,
+// or ErrImplausibleCalibration if the result fails sanity checks.
+// If it returns error, the previous published parameters are kept.
+func CalibrateWithOptions(opts CalibrationOptions) (CalibrationResult, error) {
+	return calibrate(context.Background(), opts)
+}
+
+func calibrate(ctx context.Context, opts CalibrationOptions) (CalibrationResult, error) {
+	throttle := newThrottleMonitor()
+	defer throttle.close()
+
+	return calibrateWith(ctx, opts, throttle)
+}
+
+// calibrateWith calibrates as calibrate, throttling is detected by throttle (nil means it isn't detected).
+func calibrateWith(ctx context.Context, opts CalibrationOptions, throttle *throttleMonitor) (CalibrationResult, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return CalibrationResult{}, err
+	}
+
+	if !isHardwareSupported() {
+		return CalibrationResult{}, ErrUnsupported
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	sp := &sampler{retries: opts.Retries, ref: opts.Reference, throttle: throttle}
+
+	var ss []Sample
+
+	// Samples on a dedicated thread, which is terminated with the goroutine,
+	// so pinning & priority won't leak to other goroutines.
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		pinThread(opts.RaisePriority)
+
+		ss, err = sp.collect(ctx, opts.Samples, opts.Interval)
+	}()
+
+	<-done
+
+	if err != nil {
+		return CalibrationResult{}, err
+	}
+
+	good := sp.dropWide(ss)
+	if len(good) < len(ss)/2 {
+		return CalibrationResult{}, fmt.Errorf("tsc: only %d of %d samples are good: %w", len(good), len(ss), ErrTooFewSamples)
+	}
+
+	e, err := opts.Estimator.Estimate(good)
+	if err != nil {
+		return CalibrationResult{}, err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return CalibrationResult{}, err
+	}
+
+	check := checkCalibration
+	if opts.SkipChangeCheck {
+		check = checkPlausible
+	}
+
+	if err = check(e.Offset, e.Coeff); err != nil {
+		return CalibrationResult{}, err
+	}
+
+	sp.publish(e.Offset, e.Coeff)
+	acceptCalibration(e.Coeff)
+
+	mid := good[0].Counter + (good[len(good)-1].Counter-good[0].Counter)/2
+	updateErrorBound(int64(float64(mid)*e.Coeff)+e.Offset, e.Coeff, e.Error, good)
+	history.record(mid, e.Coeff, e.Offset)
+
+	return CalibrationResult{
+		Coeff:     e.Coeff,
+		Offset:    e.Offset,
+		Frequency: 1e9 / e.Coeff,
+		Error:     e.Error,
+		Samples:   len(good),
+		Rejected:  e.Rejected,
+		Dropped:   len(ss) - len(good),
+		Throttled: sp.throttled,
+		Counter:   mid,
+	}, nil
+}
+
+// CalibrateWithCoeff calibrates coefficient to wall_clock by variables.
+//
+// Not thread safe, only for testing.
+func CalibrateWithCoeff(coeff float64) {
+	if !Supported() {
+		return
+	}
+
+	_, tsc, sys := getClosestTSCSys(getClosestTSCSysRetries)
+	off := sys - int64(float64(tsc)*coeff)
+	publish(off, coeff)
+}
+
+// publishMu serializes writers of published parameters.
+var publishMu sync.Mutex
+
+// publish stores offset & coeff for all UnixNano implementations.
+//
+// Per-node offsets (if enabled) aren't sampled again, they are moved with the global conversion at the current counter,
+// only full calibrations (see sampler.publish) sample them.
+func publish(offset int64, coeff float64) {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	c := GetInOrder()
+	step := conversionStep(c, offset, coeff)
+
+	storeParams(offset, coeff)
+
+	if IsPerNodeOffset() {
+		slewPerNode(c, coeff, step)
+	}
+}
+
+// publishChecked is publish for results which passed checkCalibration.
+func publishChecked(offset int64, coeff float64) {
+	publish(offset, coeff)
+	acceptCalibration(coeff)
+}
+
+// publish works as the package-level publish, but per-node offsets (if enabled) are sampled again by sp,
+// so they are against the same reference clock, it's used by full calibrations.
+func (sp *sampler) publish(offset int64, coeff float64) {
+	// Nodes are measured before storing anything, so UnixNano on them doesn't jump back & forth while measuring.
+	var ns *nodeOffsets
+	if IsPerNodeOffset() {
+		ns = measurePerNode(sp, coeff)
+	}
+
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	c := GetInOrder()
+	step := conversionStep(c, offset, coeff)
+
+	storeParams(offset, coeff)
+
+	if ns != nil {
+		storePerNode(ns, c, offset, coeff, step)
+	}
+}
+
+// conversionStep returns how much the global conversion at counter c changes by publishing offset & coeff,
+// it's called with publishMu held.
+func conversionStep(c, offset int64, coeff float64) int64 {
+	oldOffset, oldCoeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	return int64(float64(c)*coeff) + offset - (int64(float64(c)*oldCoeff) + oldOffset)
+}
+
+// storeParams stores offset & coeff, and calibrates used clocks, it's called with publishMu held.
+func storeParams(offset int64, coeff float64) {
+	storeOffsetCoeff(OffsetCoeffAddr, offset, coeff)
+	storeOffsetFCoeff(OffsetCoeffFAddr, float64(offset), coeff)
+	generation.Add(1)
+	calibrateClocks(coeff)
+}
+
+// slew changes coeff of published parameters while keeping the conversion continuous at counter c,
+// and steps the conversion by step nanoseconds at the same time.
+// Per-node parameters are adjusted in the same way without sampling again.
+func slew(c int64, coeff float64, step int64) {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	offset, old := LoadOffsetCoeff(OffsetCoeffAddr)
+	offset = slewOffset(c, offset, old, coeff, step)
+	storeOffsetCoeff(OffsetCoeffAddr, offset, coeff)
+	storeOffsetFCoeff(OffsetCoeffFAddr, float64(offset), coeff)
+	generation.Add(1)
+	slewClocks(c, coeff, step)
+
+	if IsPerNodeOffset() {
+		slewPerNode(c, coeff, step)
+	}
+}
+
+// slewPerNode slews per-node parameters as slew does, it's called with publishMu held.
+func slewPerNode(c int64, coeff float64, step int64) {
+	for i := range maxNodes {
+		addr := &NodeOffsetCoeff[i*CacheLineSize]
+		nodeOffset, nodeCoeff := LoadOffsetCoeff(addr)
+		storeOffsetCoeff(addr, slewOffset(c, nodeOffset, nodeCoeff, coeff, step), coeff)
+	}
+}
+
+// slewOffset returns the offset which makes counter c converted by coeff to
+// the same value converted by (offset, from), plus step.
+func slewOffset(c, offset int64, from, to float64, step int64) int64 {
+	return int64(float64(c)*from) + offset + step - int64(float64(c)*to)
+}
+
+// sampler takes closest pairs, and retries the ones whose bracket is too wide.
+// Zero value takes pairs with the system clock in default retries.
+type sampler struct {
+	narrowest int64            // The narrowest bracket has been seen.
+	retries   int              // Number of reference clock reads for a pair, 0 means getClosestTSCSysRetries.
+	ref       func() int64     // Reference clock, nil means the system clock.
+	throttle  *throttleMonitor // nil means throttling isn't detected.
+	throttled int              // Number of samples which are still throttled after retries.
+}
+
+// pinThread locks the calling goroutine to its OS thread, pins the thread to the CPU where it's running,
+// and raises its priority if raise is true and it's permitted.
+//
+// The thread must not be unlocked, it will be terminated with the goroutine.
+func pinThread(raise bool) {
+	runtime.LockOSThread()
+
+	if c, _ := getcpu(); c >= 0 {
+		_ = setAffinity([]int{c})
+	}
+
+	if raise {
+		_ = raisePriority()
+	}
+}
+
+// collect takes n pairs of samples, the samples of a pair are taken around a sleep of interval.
+// Returns ctx.Err() if ctx is done before finishing.
+func (sp *sampler) collect(ctx context.Context, n int, interval time.Duration) ([]Sample, error) {
+	ss := make([]Sample, 0, n*2)
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for range n {
+		s0 := sp.sample()
+
+		timer.Reset(interval)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		s1 := sp.sample()
+
+		ss = append(ss, s0, s1)
+	}
+
+	return ss, nil
+}
+
+// closest gets the closest pair.
+func (sp *sampler) closest() (minDelta, tsc, ref int64) {
+	retries, f := sp.retries, sp.ref
+	if retries == 0 {
+		retries = getClosestTSCSysRetries
+	}
+
+	if f == nil {
+		f = sysClock
+	}
+
+	return getClosestTSCRef(retries, f)
+}
+
+// sample takes a sample, samples which are throttled in all tries are marked with max uncertainty,
+// so they are dropped as too wide ones.
+func (sp *sampler) sample() Sample {
+	best := Sample{Uncertainty: math.MaxInt64}
+
+	for range sampleRetries + 1 {
+		n := sp.throttle.nrThrottled()
+
+		d, tsc, sys := sp.closest()
+		// Bracket is at least one tick, even if the counter is too slow to tell the difference.
+		d = max(d, 1)
+
+		if sp.throttle.nrThrottled() != n {
+			continue
+		}
+
+		if d < best.Uncertainty {
+			best = Sample{Counter: tsc, Reference: sys, Uncertainty: d}
+		}
+
+		if sp.narrowest == 0 || d < sp.narrowest {
+			sp.narrowest = d
+		}
+
+		if !sp.isWide(d) {
+			break
+		}
+	}
+
+	if best.Uncertainty == math.MaxInt64 {
+		sp.throttled++
+	}
+
+	return best
+}
+
+func (sp *sampler) isWide(d int64) bool {
+	// Nothing is wider than a too wide narrowest one (e.g. MaxInt64 if no pair has been found),
+	// and the multiplication would overflow.
+	if sp.narrowest > math.MaxInt64/wideBracketFactor {
+		return false
+	}
+
+	return d > wideBracketFactor*sp.narrowest
+}
+
+// dropWide returns samples whose bracket isn't too wide compared with the narrowest one of all.
+func (sp *sampler) dropWide(ss []Sample) []Sample {
+	good := make([]Sample, 0, len(ss))
+
+	for _, s := range ss {
+		if !sp.isWide(s.Uncertainty) {
+			good = append(good, s)
+		}
+	}
+
+	return good
+}
@@ -0,0 +1,343 @@
+package tsc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makeSamples makes samples on the line: reference = counter * coeff + offset, with noise in [-noise, noise] ns.
+func makeSamples(n int, coeff float64, offset, noise int64) []Sample {
+	r := rand.New(rand.NewSource(1))
+
+	ss := make([]Sample, n)
+	counter := int64(1 << 40)
+
+	for i := range ss {
+		counter += 48_000_000 + r.Int63n(1000)
+
+		ref := int64(float64(counter)*coeff) + offset
+		if noise > 0 {
+			ref += r.Int63n(2*noise+1) - noise
+		}
+
+		ss[i] = Sample{Counter: counter, Reference: ref}
+	}
+
+	return ss
+}
+
+func TestOLS(t *testing.T) {
+	t.Parallel()
+
+	coeff, offset := 1/3.0, int64(1_700_000_000_000_000_000)
+	ss := makeSamples(256, coeff, offset, 100)
+
+	e, err := OLS{}.Estimate(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(e.Coeff-coeff)/coeff > 1e-6 {
+		t.Fatalf("coeff mismatch, exp: %.16f, got: %.16f", coeff, e.Coeff)
+	}
+
+	if e.Error > 1000 {
+		t.Fatalf("error too big: %.2f", e.Error)
+	}
+
+	// Unix nanoseconds need more than 53 bits, precision shouldn't be lost in float64.
+	e, err = OLS{}.Estimate(makeSamples(256, coeff, offset, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Error > 1 {
+		t.Fatalf("error should be less than 1ns without noise, got: %.2f", e.Error)
+	}
+
+	if _, err = (OLS{}).Estimate(ss[:1]); !errors.Is(err, ErrTooFewSamples) {
+		t.Fatalf("should return ErrTooFewSamples, got: %v", err)
+	}
+}
+
+func TestOLSWeighted(t *testing.T) {
+	t.Parallel()
+
+	coeff, offset := 1/3.0, int64(1_700_000_000_000_000_000)
+
+	clean := makeSamples(256, coeff, offset, 0)
+	for i := range clean {
+		clean[i].Uncertainty = 60
+	}
+
+	// Reference clock is read 100µs late, but the bracket tells it.
+	dirty := slices.Clone(clean)
+	for i := 3; i < len(dirty); i += 16 {
+		dirty[i].Reference += 100_000
+		dirty[i].Uncertainty = 300_000
+	}
+
+	exp, err := OLS{}.Estimate(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := OLS{}.Estimate(dirty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := maxPredictionDelta(clean, exp, got); d > 10 {
+		t.Fatalf("samples with wide bracket should be weighted down, max delta: %dns", d)
+	}
+}
+
+func TestSamplerDropWide(t *testing.T) {
+	t.Parallel()
+
+	sp := sampler{narrowest: 50}
+	ss := []Sample{{Uncertainty: 50}, {Uncertainty: 120}, {Uncertainty: 200}, {Uncertainty: 201}, {Uncertainty: 5000}}
+
+	good := sp.dropWide(ss)
+	if len(good) != 3 {
+		t.Fatalf("samples wider than %d should be dropped, got: %v", wideBracketFactor*sp.narrowest, good)
+	}
+
+	// No pair has been found, the factor mustn't overflow.
+	sp = sampler{narrowest: math.MaxInt64}
+	if sp.isWide(math.MaxInt64) {
+		t.Fatal("nothing should be wider than max narrowest")
+	}
+}
+
+type countingEstimator struct {
+	samples int
+}
+
+func (c *countingEstimator) Estimate(samples []Sample) (Estimate, error) {
+	c.samples = len(samples)
+
+	return OLS{}.Estimate(samples)
+}
+
+//nolint:paralleltest
+func TestCalibrateWithOptions(t *testing.T) {
+	if !Supported() {
+		if _, err := CalibrateWithOptions(CalibrationOptions{}); !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("should return ErrUnsupported, got: %v", err)
+		}
+
+		t.Skip("tsc is unsupported")
+	}
+
+	est := new(countingEstimator)
+
+	ret, err := CalibrateWithOptions(CalibrationOptions{Estimator: est})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if est.samples == 0 || est.samples != ret.Samples {
+		t.Fatalf("estimator should be used, got %d samples, result has %d", est.samples, ret.Samples)
+	}
+
+	if ret.Samples+ret.Dropped != samples*2 {
+		t.Fatalf("samples are missing, used: %d, dropped: %d", ret.Samples, ret.Dropped)
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	if offset != ret.Offset || coeff != ret.Coeff {
+		t.Fatal("result should be published")
+	}
+
+	t.Logf("frequency: %.2f Hz, error: %.2f ns, dropped: %d", ret.Frequency, ret.Error, ret.Dropped)
+}
+
+func TestCalibrationOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range []CalibrationOptions{
+		{Samples: -1},
+		{Interval: -time.Millisecond},
+		{Retries: -1},
+		{Retries: 1},
+		{Timeout: -time.Second},
+		{Timeout: time.Second}, // Default 128 × 16ms takes longer.
+		{Samples: 10, Interval: 10 * time.Millisecond, Timeout: 50 * time.Millisecond},
+	} {
+		if _, err := CalibrateWithOptions(opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%+v should be invalid, got: %v", opts, err)
+		}
+	}
+
+	opts, err := CalibrationOptions{Samples: 6, Timeout: time.Second}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Interval != sampleDuration || opts.Retries != getClosestTSCSysRetries || opts.Estimator == nil || opts.Reference == nil {
+		t.Fatalf("defaults should be filled, got: %+v", opts)
+	}
+}
+
+//nolint:paralleltest
+func TestCalibrateWithReference(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	defer Calibrate()
+
+	shift := time.Hour.Nanoseconds()
+	ref := func() int64 { return time.Now().UnixNano() + shift }
+
+	start := time.Now()
+
+	ret, err := CalibrateWithOptions(CalibrationOptions{Samples: 6, Retries: 64, Reference: ref, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cost := time.Since(start); cost > 500*time.Millisecond {
+		t.Fatalf("short calibration takes too long: %s", cost)
+	}
+
+	if ret.Samples+ret.Dropped != 12 {
+		t.Fatalf("samples are missing, used: %d, dropped: %d", ret.Samples, ret.Dropped)
+	}
+
+	if delta := UnixNano() - ref(); delta > 100*time.Microsecond.Nanoseconds() || delta < -100*time.Microsecond.Nanoseconds() {
+		t.Fatalf("should be calibrated to reference, delta: %dns", delta)
+	}
+}
+
+//nolint:paralleltest
+func TestCalibrateContext(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if _, err := CalibrateContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should return ctx.Err(), got: %v", err)
+	}
+
+	if cost := time.Since(start); cost > 200*time.Millisecond {
+		t.Fatalf("should stop promptly, but takes: %s", cost)
+	}
+
+	if o, c := LoadOffsetCoeff(OffsetCoeffAddr); o != offset || c != coeff {
+		t.Fatal("previous parameters should be kept")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := CalibrateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("should return ctx.Err(), got: %v", err)
+	}
+}
+
+// loadSamples loads samples recorded by getClosestTSCSys in testdata.
+func loadSamples(t *testing.T) []Sample {
+	t.Helper()
+
+	d, err := os.ReadFile("testdata/samples.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ss []Sample
+
+	for _, line := range strings.Split(string(d), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var s Sample
+		if _, err = fmt.Sscan(line, &s.Counter, &s.Reference, &s.Uncertainty); err != nil {
+			t.Fatal(err)
+		}
+
+		ss = append(ss, s)
+	}
+
+	return ss
+}
+
+// maxPredictionDelta returns the max delta of predictions made by e0 & e1 on samples.
+func maxPredictionDelta(ss []Sample, e0, e1 Estimate) int64 {
+	maxDelta := int64(0)
+
+	for _, s := range ss {
+		d := residual(s, e0.Coeff, e0.Offset) - residual(s, e1.Coeff, e1.Offset)
+		maxDelta = max(maxDelta, d, -d)
+	}
+
+	return maxDelta
+}
+
+func TestRobust(t *testing.T) {
+	t.Parallel()
+
+	clean := loadSamples(t)
+
+	exp, err := OLS{}.Estimate(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Robust{}.Estimate(clean)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := maxPredictionDelta(clean, exp, got); d > 1000 {
+		t.Fatalf("robust should be close to OLS on clean samples, max delta: %dns", d)
+	}
+
+	// Injects outliers: reference clock is read 100µs late, like a preemption happened in the bracket.
+	dirty := slices.Clone(clean)
+	injected := 0
+
+	for i := 3; i < len(dirty); i += 16 {
+		dirty[i].Reference += 100_000
+		injected++
+	}
+
+	ols, err := OLS{}.Estimate(dirty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = Robust{}.Estimate(dirty)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Rejected < injected {
+		t.Fatalf("outliers should be rejected, injected: %d, rejected: %d", injected, got.Rejected)
+	}
+
+	olsDelta, robustDelta := maxPredictionDelta(clean, exp, ols), maxPredictionDelta(clean, exp, got)
+	if robustDelta > 1000 || robustDelta >= olsDelta {
+		t.Fatalf("robust should resist outliers, max delta of robust: %dns, OLS: %dns", robustDelta, olsDelta)
+	}
+
+	t.Logf("injected: %d, rejected: %d, max delta of robust: %dns, OLS: %dns",
+		injected, got.Rejected, robustDelta, olsDelta)
+}
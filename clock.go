@@ -0,0 +1,232 @@
+package tsc
+
+import (
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a POSIX clock which could be got at counter cost.
+type Clock int
+
+// Clocks besides CLOCK_REALTIME (see UnixNano).
+const (
+	// ClockMonotonic is CLOCK_MONOTONIC, it never steps (but is slewed by NTP).
+	ClockMonotonic Clock = iota
+	// ClockBoottime is CLOCK_BOOTTIME, it's CLOCK_MONOTONIC including time spent in suspend.
+	ClockBoottime
+	// ClockTAI is CLOCK_TAI, it's CLOCK_REALTIME plus the TAI offset (which is set by NTP daemons).
+	ClockTAI
+	numClocks
+)
+
+// Configs of clock calibration.
+const (
+	clockSamples = 8
+	clockRetries = 64
+	// maxClockSkew is the max rate (relative to coeff) of slewing CLOCK_MONOTONIC & CLOCK_BOOTTIME
+	// toward a new calibration, it's the same as the kernel's max NTP slew rate (500ppm).
+	maxClockSkew = 500e-6
+	// clockStepThreshold is the correction (in ns) beyond which CLOCK_MONOTONIC & CLOCK_BOOTTIME are stepped forward
+	// instead of slewed, e.g. after suspend (for CLOCK_BOOTTIME) or VM migration,
+	// slewing an hour at maxClockSkew takes 83 days. Backward corrections are always slewed.
+	clockStepThreshold = 1e6
+	// minClockSlew is the min duration of slewing (in ns), for avoiding slewing at max rate for small corrections
+	// when calibrations are frequent (e.g. Online.Observe).
+	minClockSlew = 1e9
+)
+
+// clockParams is the statically allocated block for offset & coeff of each Clock, see params for details.
+var clockParams [(numClocks + 1) * CacheLineSize]byte
+
+// clockUsed indicates the Clock has been used, only used ones are calibrated by publish.
+var clockUsed [numClocks]atomic.Bool
+
+// clockSlews are the slewing states of clocks which never go backwards, guarded by publishMu.
+var clockSlews [numClocks]struct {
+	skew float64 // Relative rate correction, coeff of the clock is coeff * (1 + skew).
+	at   int64   // Counter of the last calibration.
+	end  int64   // Counter when the correction has been absorbed.
+}
+
+// processStart is the base of fallback clocks.
+var processStart = time.Now()
+
+func clockAddr(clk Clock) *byte {
+	return &clockParams[paramsOffset+int(clk)*CacheLineSize]
+}
+
+// MonotonicNano returns CLOCK_MONOTONIC in nanoseconds,
+// computed from the counter with its own offset calibrated against CLOCK_MONOTONIC.
+//
+// It's calibrated at first use, and recalibrated by each calibration then,
+// but it never goes backwards: it's kept continuous, and slewed toward the new calibration
+// at most 500ppm faster or slower, or stepped forward if it's behind by more than 1ms
+// (e.g. CLOCK_BOOTTIME after suspend).
+// It isn't stepped by wall clock adjustments as UnixNano either.
+//
+// If the counter is unsupported, it falls back to clock_gettime,
+// or the Go monotonic clock since process start on platforms without clock_gettime (Linux only).
+func MonotonicNano() int64 {
+	return clockNano(ClockMonotonic)
+}
+
+// BoottimeNano returns CLOCK_BOOTTIME in nanoseconds, see MonotonicNano for details.
+//
+// It falls back in the same way as MonotonicNano.
+func BoottimeNano() int64 {
+	return clockNano(ClockBoottime)
+}
+
+// TAINano returns CLOCK_TAI in nanoseconds, see MonotonicNano for details.
+//
+// If the counter is unsupported, it falls back to clock_gettime,
+// or TAINanoLeap on platforms without clock_gettime (Linux only).
+func TAINano() int64 {
+	return clockNano(ClockTAI)
+}
+
+func clockNano(clk Clock) int64 {
+	if !Supported() || !hasClockGettime {
+		return fallbackClockNano(clk)
+	}
+
+	c := NowTicks()
+
+	offset, coeff := LoadOffsetCoeff(clockAddr(clk))
+	if coeff == 0 {
+		calibrateClock(clk)
+
+		offset, coeff = LoadOffsetCoeff(clockAddr(clk))
+	}
+
+	return int64(float64(c)*coeff) + offset
+}
+
+func fallbackClockNano(clk Clock) int64 {
+	if hasClockGettime {
+		return clockGettime(clk)
+	}
+
+	switch clk {
+	case ClockTAI:
+		return TAINanoLeap()
+	default:
+		return int64(time.Since(processStart))
+	}
+}
+
+// calibrateClock calibrates clk at first use.
+func calibrateClock(clk Clock) {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	if clockUsed[clk].Load() {
+		return
+	}
+
+	_, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	storeOffsetCoeff(clockAddr(clk), clockOffset(clk, coeff), coeff)
+	clockSlews[clk].skew = 0
+	clockSlews[clk].at = GetInOrder()
+	clockSlews[clk].end = clockSlews[clk].at
+	clockUsed[clk].Store(true)
+}
+
+// calibrateClocks calibrates used clocks with coeff, it's called by publish with publishMu held.
+//
+// CLOCK_TAI is stepped to the new calibration as CLOCK_REALTIME.
+// Others are kept continuous at the current counter, and slewed toward the new calibration
+// in the duration since the last calibration (at least minClockSlew, at most maxClockSkew in rate),
+// so they never go backwards.
+func calibrateClocks(coeff float64) {
+	for clk := range numClocks {
+		if !clockUsed[clk].Load() {
+			continue
+		}
+
+		addr := clockAddr(clk)
+		measured := clockOffset(clk, coeff)
+
+		if clk == ClockTAI {
+			storeOffsetCoeff(addr, measured, coeff)
+
+			continue
+		}
+
+		c := GetInOrder()
+		st := &clockSlews[clk]
+		offset, old := LoadOffsetCoeff(addr)
+
+		// Correction (in ns) from the continuous conversion to the measured one at c.
+		correction := float64(measured - slewOffset(c, offset, old, coeff, 0))
+
+		// Stepping forward never goes backwards.
+		if correction > clockStepThreshold {
+			st.skew, st.at, st.end = 0, c, c
+			storeOffsetCoeff(addr, measured, coeff)
+
+			continue
+		}
+
+		elapsed := max(float64(c-st.at)*coeff, minClockSlew)
+
+		st.skew = max(min(correction/elapsed, maxClockSkew), -maxClockSkew)
+		st.at = c
+		st.end = c + int64(math.Abs(correction/st.skew)/coeff)
+
+		if st.skew == 0 {
+			st.end = c
+		}
+
+		clkCoeff := coeff * (1 + st.skew)
+		storeOffsetCoeff(addr, slewOffset(c, offset, old, clkCoeff, 0), clkCoeff)
+	}
+}
+
+// slewClocks slews used clocks as slew does,
+// only CLOCK_TAI is stepped with CLOCK_REALTIME, it's called by slew with publishMu held.
+//
+// Slewing toward the last calibration (see calibrateClocks) goes on until the correction has been absorbed.
+func slewClocks(c int64, coeff float64, step int64) {
+	for clk := range numClocks {
+		if !clockUsed[clk].Load() {
+			continue
+		}
+
+		clkStep := int64(0)
+		clkCoeff := coeff
+
+		if clk == ClockTAI {
+			clkStep = step
+		} else {
+			st := &clockSlews[clk]
+			if c >= st.end {
+				st.skew = 0
+			}
+
+			clkCoeff = coeff * (1 + st.skew)
+		}
+
+		addr := clockAddr(clk)
+		offset, old := LoadOffsetCoeff(addr)
+		storeOffsetCoeff(addr, slewOffset(c, offset, old, clkCoeff, clkStep), clkCoeff)
+	}
+}
+
+// clockOffset gets the offset of clk with coeff by closest pairs.
+func clockOffset(clk Clock, coeff float64) int64 {
+	ref := func() int64 { return clockGettime(clk) }
+
+	offs := make([]int64, clockSamples)
+
+	for i := range offs {
+		_, tsc, ns := getClosestTSCRef(clockRetries, ref)
+		offs[i] = ns - int64(float64(tsc)*coeff)
+	}
+
+	slices.Sort(offs)
+
+	return offs[len(offs)/2]
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package tsc
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// hasClockGettime indicates clockGettime is supported.
+const hasClockGettime = true
+
+// clockIDs are the clockid_t of each Clock.
+var clockIDs = [numClocks]uintptr{
+	ClockMonotonic: 1,  // CLOCK_MONOTONIC
+	ClockBoottime:  7,  // CLOCK_BOOTTIME
+	ClockTAI:       11, // CLOCK_TAI
+}
+
+// clockGettime returns clk in nanoseconds by clock_gettime syscall, it returns 0 if it fails.
+func clockGettime(clk Clock) int64 {
+	var ts syscall.Timespec
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_CLOCK_GETTIME, clockIDs[clk], uintptr(unsafe.Pointer(&ts)), 0)
+	if errno != 0 {
+		return 0
+	}
+
+	return ts.Nano()
+}
+
+// kernelTAIOffset returns the kernel's TAI offset (TAI - UTC in seconds) by adjtimex,
+// it's 0 if no NTP daemon sets it.
+func kernelTAIOffset() (int64, bool) {
+	var tx syscall.Timex
+
+	if _, err := syscall.Adjtimex(&tx); err != nil {
+		return 0, false
+	}
+
+	return int64(tx.Tai), true
+}
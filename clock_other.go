@@ -0,0 +1,16 @@
+//go:build !linux
+
+package tsc
+
+// hasClockGettime indicates clockGettime is supported.
+const hasClockGettime = false
+
+// clockGettime isn't supported on this platform.
+func clockGettime(Clock) int64 {
+	return 0
+}
+
+// kernelTAIOffset isn't supported on this platform.
+func kernelTAIOffset() (int64, bool) {
+	return 0, false
+}
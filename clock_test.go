@@ -0,0 +1,149 @@
+package tsc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClocks(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() || !hasClockGettime {
+		t.Skip("tsc or clock_gettime is unsupported")
+	}
+
+	// Slewing toward calibrations made by other tests may be in progress.
+	resetClocks()
+
+	for _, clk := range []Clock{ClockMonotonic, ClockBoottime, ClockTAI} {
+		_ = clockNano(clk) // Calibrates at first use.
+
+		for range 1000 {
+			t0 := clockGettime(clk)
+			ns := clockNano(clk)
+			t1 := clockGettime(clk)
+
+			// Calibration error & out-of-order reading.
+			const tolerance = 10_000
+			if ns < t0-tolerance || ns > t1+tolerance {
+				t.Fatalf("clock %d mismatch: %d not in [%d, %d]", clk, ns, t0, t1)
+			}
+		}
+	}
+}
+
+//nolint:paralleltest // Calibrate changes global parameters.
+func TestMonotonicNanoAcrossCalibrations(t *testing.T) {
+	if !Supported() || !hasClockGettime {
+		t.Skip("tsc or clock_gettime is unsupported")
+	}
+
+	// Reads concurrently, so a backwards step at publishing is caught.
+	// Tolerance is for a reader preempted between reading the counter & parameters,
+	// which converts an old counter by new parameters (or vice versa).
+	const tolerance = 500
+
+	var (
+		backwards atomic.Int64
+		stop      atomic.Bool
+		wg        sync.WaitGroup
+	)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		last := MonotonicNano()
+		for !stop.Load() {
+			ns := MonotonicNano()
+			if last-ns > backwards.Load() {
+				backwards.Store(last - ns)
+			}
+
+			last = ns
+		}
+	}()
+
+	_, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	for range 5 {
+		// A coeff 100ppm too large makes the conversion run fast (5µs in 50ms),
+		// the next calibration corrects it backwards.
+		CalibrateWithCoeff(coeff * (1 + 1e-4))
+		time.Sleep(50 * time.Millisecond)
+
+		if _, err := CalibrateWithOptions(CalibrationOptions{Samples: 16, Interval: 4 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop.Store(true)
+	wg.Wait()
+
+	if b := backwards.Load(); b > tolerance {
+		t.Fatalf("monotonic clock goes backwards by %dns", b)
+	}
+
+	// It keeps tracking CLOCK_MONOTONIC (within slewing).
+	if d := MonotonicNano() - clockGettime(ClockMonotonic); d > 1_000_000 || d < -1_000_000 {
+		t.Fatalf("monotonic clock is off by %dns", d)
+	}
+
+	resetClocks()
+}
+
+//nolint:paralleltest // Calibrations change global parameters.
+func TestBoottimeNanoStepsForward(t *testing.T) {
+	if !Supported() || !hasClockGettime {
+		t.Skip("tsc or clock_gettime is unsupported")
+	}
+
+	defer resetClocks()
+
+	_ = BoottimeNano()
+
+	// Misses an hour (e.g. in suspend), it's stepped forward instead of slewed for days.
+	publishMu.Lock()
+	offset, coeff := LoadOffsetCoeff(clockAddr(ClockBoottime))
+	storeOffsetCoeff(clockAddr(ClockBoottime), offset-int64(time.Hour), coeff)
+	publishMu.Unlock()
+
+	before := BoottimeNano()
+
+	if _, err := CalibrateWithOptions(CalibrationOptions{Samples: 16, Interval: 4 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := BoottimeNano()
+	if ns < before {
+		t.Fatalf("boottime clock goes backwards from %d to %d", before, ns)
+	}
+
+	if d := ns - clockGettime(ClockBoottime); d > 1_000_000 || d < -1_000_000 {
+		t.Fatalf("boottime clock should be stepped forward, it's off by %dns", d)
+	}
+}
+
+// resetClocks makes clocks calibrated again at next use.
+func resetClocks() {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	for clk := range numClocks {
+		storeOffsetCoeff(clockAddr(clk), 0, 0)
+		clockUsed[clk].Store(false)
+	}
+}
+
+func BenchmarkMonotonicNano(b *testing.B) {
+	if !Supported() {
+		b.Skip("tsc is unsupported")
+	}
+
+	for range b.N {
+		_ = MonotonicNano()
+	}
+}
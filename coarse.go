@@ -0,0 +1,70 @@
+package tsc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCoarsePeriod is the default refreshing period of the coarse clock.
+const defaultCoarsePeriod = time.Millisecond
+
+// ErrCoarseRunning is returned by RunCoarse when the coarse clock is already running.
+var ErrCoarseRunning = errors.New("tsc: coarse clock is already running")
+
+// coarse is the cached UnixNano (0 if the coarse clock isn't running).
+// The paddings keep it in a cache line by itself, so readers never miss the cache because of writes to neighbours.
+var coarse struct {
+	_  [CacheLineSize]byte
+	ns atomic.Int64
+	_  [CacheLineSize - 8]byte
+}
+
+var coarseRunning atomic.Bool
+
+// CoarseUnixNano returns UnixNano cached by RunCoarse, it's a single atomic load,
+// for call sites which only need about millisecond precision but read the clock very often.
+//
+// Staleness bounds: the result is behind UnixNano by at most the refreshing period
+// plus the delay of waking the refreshing goroutine up (timer slack, scheduling latency & GC pauses),
+// which is usually tens of µs but unbounded under CPU starvation. It's never ahead of UnixNano,
+// and it's non-decreasing between calibrations (a calibration may step UnixNano backwards by its error).
+//
+// It falls back to UnixNano if the coarse clock isn't running.
+func CoarseUnixNano() int64 {
+	if ns := coarse.ns.Load(); ns != 0 {
+		return ns
+	}
+
+	return UnixNano()
+}
+
+// RunCoarse refreshes the coarse clock (see CoarseUnixNano) from UnixNano every period until ctx is done,
+// period <= 0 means 1ms. The coarse clock is stopped (CoarseUnixNano falls back to UnixNano) after it returns.
+//
+// Returns ErrCoarseRunning if another RunCoarse is running, or ctx.Err() after ctx is done.
+func RunCoarse(ctx context.Context, period time.Duration) error {
+	if period <= 0 {
+		period = defaultCoarsePeriod
+	}
+
+	if !coarseRunning.CompareAndSwap(false, true) {
+		return ErrCoarseRunning
+	}
+	defer coarseRunning.Store(false)
+	defer coarse.ns.Store(0)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		coarse.ns.Store(UnixNano())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package tsc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+//nolint:paralleltest // Only one coarse clock could run.
+func TestCoarseUnixNano(t *testing.T) {
+	const period = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- RunCoarse(ctx, period) }()
+
+	// Waits for the first refreshing.
+	for coarse.ns.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := RunCoarse(ctx, period); !errors.Is(err, ErrCoarseRunning) {
+		t.Fatalf("error mismatch: exp: %v, got: %v", ErrCoarseRunning, err)
+	}
+
+	// Staleness bound with generous scheduling latency for CI.
+	const bound = period + 50*time.Millisecond
+
+	for range 1000 {
+		ns := CoarseUnixNano()
+		now := UnixNano()
+
+		if d := time.Duration(now - ns); d < -time.Microsecond || d > bound {
+			t.Fatalf("staleness out of bound: %s", d)
+		}
+	}
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error mismatch: exp: %v, got: %v", context.Canceled, err)
+	}
+
+	// Falls back to UnixNano after stopped.
+	before := UnixNano()
+	if ns := CoarseUnixNano(); ns < before {
+		t.Fatalf("should fall back to UnixNano after stopped: %d < %d", ns, before)
+	}
+}
+
+func BenchmarkCoarseUnixNano(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		_ = RunCoarse(ctx, 0)
+	}()
+
+	// Waits for stopping, so the next round of benchmark could start its own.
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for coarse.ns.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = CoarseUnixNano()
+		}
+	})
+}
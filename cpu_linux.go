@@ -0,0 +1,99 @@
+//go:build linux
+
+package tsc
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// cpuSetWords is the size of cpu_set_t in uint64 (1024 CPUs), same as glibc.
+const cpuSetWords = 1024 / 64
+
+type cpuSet [cpuSetWords]uint64
+
+// getcpu returns the CPU & NUMA node which the calling thread is running on.
+//
+// The result may be stale as soon as it returns, because the thread could be migrated.
+func getcpu() (cpu, node int) {
+	var c, n uint32
+
+	_, _, errno := syscall.RawSyscall(sysGetcpu, uintptr(unsafe.Pointer(&c)), uintptr(unsafe.Pointer(&n)), 0)
+	if errno != 0 {
+		return -1, -1
+	}
+
+	return int(c), int(n)
+}
+
+// setAffinity pins the calling thread to cpus.
+//
+// The caller should lock OS thread first.
+func setAffinity(cpus []int) error {
+	var set cpuSet
+
+	for _, c := range cpus {
+		if c >= 0 && c < cpuSetWords*64 {
+			set[c/64] |= 1 << (c % 64)
+		}
+	}
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(set), uintptr(unsafe.Pointer(&set)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+// numaNodes returns CPUs of each NUMA node by sysfs.
+// Node without CPU (e.g. memory only node) is ignored.
+func numaNodes() (map[int][]int, error) {
+	const nodePath = "/sys/devices/system/node"
+
+	dirs, err := filepath.Glob(filepath.Join(nodePath, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dirs) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	nodes := make(map[int][]int, len(dirs))
+
+	for _, dir := range dirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+
+		d, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+
+		cpus, err := parseCPUList(string(d))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(cpus) > 0 {
+			nodes[node] = cpus
+		}
+	}
+
+	return nodes, nil
+}
+
+// raisePriority raises the scheduling priority of the calling thread to the highest nice value,
+// it needs CAP_SYS_NICE (or RLIMIT_NICE).
+//
+// The caller should lock OS thread first.
+func raisePriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), -20)
+}
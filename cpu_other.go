@@ -0,0 +1,28 @@
+//go:build !linux
+
+package tsc
+
+import (
+	"errors"
+	"os"
+)
+
+// getcpu returns -1 for both CPU & NUMA node, because there is no getcpu on this platform.
+func getcpu() (cpu, node int) {
+	return -1, -1
+}
+
+// setAffinity isn't supported on this platform.
+func setAffinity([]int) error {
+	return ErrPerNodeUnsupported
+}
+
+// numaNodes isn't supported on this platform.
+func numaNodes() (map[int][]int, error) {
+	return nil, os.ErrNotExist
+}
+
+// raisePriority isn't supported on this platform.
+func raisePriority() error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,231 @@
+package tsc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Default configs of Discipline.
+const (
+	defaultDisciplineInterval = 2 * time.Second
+	defaultSlewTime           = 8 * defaultDisciplineInterval
+	defaultStepThreshold      = time.Millisecond
+	// phaseNoise is the process noise of offset in ns²/s.
+	phaseNoise = 100
+	// frequencyNoise is the process noise of frequency (random walk) in 1/s,
+	// about 0.1ppm wander in 5 minutes, which is usual for consumer-grade crystals.
+	frequencyNoise = 1e-17
+	// Initial uncertainties of offset (ns) & frequency.
+	initialPhaseStdDev     = 1000
+	initialFrequencyStdDev = 1e-6
+)
+
+// DisciplineOptions are options of Discipline.
+// Zero value means default for each field.
+type DisciplineOptions struct {
+	// Interval is the interval between two probes.
+	// 2s by default.
+	Interval time.Duration
+	// SlewTime is the time constant of offset correction:
+	// offset is corrected by adjusting frequency slightly instead of stepping,
+	// so the conversion is continuous (it won't go backwards).
+	// 16s by default.
+	SlewTime time.Duration
+	// StepThreshold is the offset beyond which the conversion is stepped instead of slewed,
+	// e.g. after the wall clock has been stepped.
+	// 1ms by default.
+	StepThreshold time.Duration
+}
+
+// DisciplineState is the state estimated by Discipline.
+type DisciplineState struct {
+	Offset          float64 // Offset of the published conversion to reference clock in nanoseconds.
+	OffsetStdDev    float64 // Standard deviation of Offset in nanoseconds.
+	Frequency       float64 // Frequency error of the published conversion (e.g. 1e-6 is 1ppm fast).
+	FrequencyStdDev float64 // Standard deviation of Frequency.
+}
+
+// Discipline disciplines the published conversion continuously,
+// in the spirit of chrony or ntpd's PLL/FLL.
+//
+// Periodic batch recalibration makes accuracy saw-tooth between runs,
+// Discipline takes a cheap closest-pair probe every Interval instead,
+// tracks both offset & frequency error of the published conversion by a Kalman filter,
+// and corrects them incrementally, so drift stays bounded without blocking calibration bursts.
+//
+// It needs an initial calibration (which is done in initialization),
+// and there is no need to run Calibrate periodically while it's running.
+type Discipline struct {
+	opts DisciplineOptions
+
+	mu          sync.Mutex
+	kf          kalman
+	lastCounter int64
+
+	// Hooks for testing.
+	probe  func() Sample
+	load   func() (offset int64, coeff float64)
+	adjust func(c int64, coeff float64, step int64)
+	bound  func(at int64, coeff, base, rate float64)
+}
+
+// NewDiscipline creates a Discipline with options.
+func NewDiscipline(opts DisciplineOptions) *Discipline {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultDisciplineInterval
+	}
+
+	if opts.SlewTime <= 0 {
+		opts.SlewTime = defaultSlewTime
+	}
+
+	if opts.StepThreshold <= 0 {
+		opts.StepThreshold = defaultStepThreshold
+	}
+
+	sp := new(sampler)
+
+	return &Discipline{
+		opts:   opts,
+		probe:  sp.sample,
+		load:   func() (int64, float64) { return LoadOffsetCoeff(OffsetCoeffAddr) },
+		adjust: slew,
+		bound:  setErrorBound,
+	}
+}
+
+// Run disciplines the conversion every Interval until ctx is done.
+//
+// Returns ErrUnsupported if the counter is unsupported, or ctx.Err() after ctx is done.
+func (d *Discipline) Run(ctx context.Context) error {
+	if !Supported() {
+		return ErrUnsupported
+	}
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Update(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Update takes a probe and corrects the published conversion once.
+// It's used by Run, and could be driven by an existing periodic loop instead.
+func (d *Discipline) Update() (DisciplineState, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s := d.probe()
+
+	offset, coeff := d.load()
+	if coeff == 0 {
+		return DisciplineState{}, ErrUnsupported
+	}
+
+	z := float64(residual(s, coeff, offset))
+	// Reference clock was read at some point in the bracket, uniform distribution.
+	width := float64(s.Uncertainty) * coeff
+	r := width*width/12 + 1
+
+	if d.lastCounter == 0 {
+		d.kf.init(z, initialPhaseStdDev*initialPhaseStdDev+r, initialFrequencyStdDev*initialFrequencyStdDev)
+	} else {
+		dt := float64(s.Counter-d.lastCounter) * coeff
+		d.kf.predict(dt)
+		d.kf.update(z, r)
+	}
+
+	d.lastCounter = s.Counter
+
+	state := d.kf.state()
+
+	// Steps the offset if it's too big, slews it otherwise.
+	step := int64(0)
+	if math.Abs(d.kf.x[0]) > float64(d.opts.StepThreshold) {
+		step = int64(d.kf.x[0])
+	}
+
+	delta := d.kf.x[1] + (d.kf.x[0]-float64(step))/float64(d.opts.SlewTime)
+	d.adjust(s.Counter, coeff*(1+delta), step)
+
+	// State is relative to the published conversion, which has been changed.
+	d.kf.x[0] -= float64(step)
+	d.kf.x[1] -= delta
+
+	// The left offset is being slewed out, the error is bounded by it plus the uncertainty.
+	d.bound(s.Reference, coeff*(1+delta),
+		math.Abs(d.kf.x[0])+errorSigmas*math.Sqrt(d.kf.p[0][0]),
+		math.Abs(d.kf.x[1])+errorSigmas*math.Sqrt(d.kf.p[1][1]))
+
+	return state, nil
+}
+
+// State returns the latest state estimated by Discipline.
+func (d *Discipline) State() DisciplineState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.kf.state()
+}
+
+// kalman tracks offset (ns) & frequency error (ns/ns) of the published conversion to reference clock.
+// The model is: offset(t+dt) = offset(t) + frequency(t)*dt, frequency(t+dt) = frequency(t).
+type kalman struct {
+	x [2]float64    // Offset, frequency.
+	p [2][2]float64 // Covariance.
+}
+
+func (k *kalman) init(offset, offsetVar, frequencyVar float64) {
+	k.x = [2]float64{offset, 0}
+	k.p = [2][2]float64{{offsetVar, 0}, {0, frequencyVar}}
+}
+
+// predict predicts state after dt nanoseconds.
+func (k *kalman) predict(dt float64) {
+	k.x[0] += k.x[1] * dt
+
+	// P = F*P*F' + Q, F = [[1, dt], [0, 1]].
+	p00 := k.p[0][0] + dt*(k.p[1][0]+k.p[0][1]) + dt*dt*k.p[1][1]
+	p01 := k.p[0][1] + dt*k.p[1][1]
+	p10 := k.p[1][0] + dt*k.p[1][1]
+	p11 := k.p[1][1]
+
+	sec := dt / 1e9
+	k.p = [2][2]float64{{p00 + phaseNoise*sec, p01}, {p10, p11 + frequencyNoise*sec}}
+}
+
+// update updates state by measured offset z with variance r.
+func (k *kalman) update(z, r float64) {
+	y := z - k.x[0]
+	s := k.p[0][0] + r
+	k0, k1 := k.p[0][0]/s, k.p[1][0]/s
+
+	k.x[0] += k0 * y
+	k.x[1] += k1 * y
+
+	// P = (I - K*H)*P, H = [1, 0].
+	k.p = [2][2]float64{
+		{(1 - k0) * k.p[0][0], (1 - k0) * k.p[0][1]},
+		{k.p[1][0] - k1*k.p[0][0], k.p[1][1] - k1*k.p[0][1]},
+	}
+}
+
+func (k *kalman) state() DisciplineState {
+	return DisciplineState{
+		Offset:          k.x[0],
+		OffsetStdDev:    math.Sqrt(k.p[0][0]),
+		Frequency:       k.x[1],
+		FrequencyStdDev: math.Sqrt(k.p[1][1]),
+	}
+}
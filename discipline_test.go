@@ -0,0 +1,123 @@
+package tsc
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// syntheticClock is a counter running at the true line: reference = counter * coeff + offset,
+// with published parameters which are disciplined.
+type syntheticClock struct {
+	r       *rand.Rand
+	coeff   float64
+	offset  int64
+	counter int64
+
+	pubCoeff  float64
+	pubOffset int64
+
+	// Error bound set by Discipline.
+	boundBase float64
+	boundRate float64
+}
+
+func newDisciplineForClock(c *syntheticClock, opts DisciplineOptions) *Discipline {
+	d := NewDiscipline(opts)
+	interval := int64(float64(d.opts.Interval) / c.coeff)
+
+	d.probe = func() Sample {
+		c.counter += interval
+		// Reference is read somewhere in a 100 cycles bracket.
+		return Sample{
+			Counter:     c.counter,
+			Reference:   int64(float64(c.counter)*c.coeff) + c.offset + c.r.Int63n(50) - 25,
+			Uncertainty: 100,
+		}
+	}
+	d.load = func() (int64, float64) { return c.pubOffset, c.pubCoeff }
+	d.adjust = func(counter int64, coeff float64, step int64) {
+		c.pubOffset = slewOffset(counter, c.pubOffset, c.pubCoeff, coeff, step)
+		c.pubCoeff = coeff
+	}
+	d.bound = func(_ int64, _, base, rate float64) {
+		c.boundBase, c.boundRate = base, rate
+	}
+
+	return d
+}
+
+// error returns published conversion - true conversion at current counter in ns.
+func (c *syntheticClock) error() int64 {
+	return int64(float64(c.counter)*c.pubCoeff) + c.pubOffset - (int64(float64(c.counter)*c.coeff) + c.offset)
+}
+
+func TestDiscipline(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name         string
+		initialError time.Duration
+		maxError     time.Duration
+	}{
+		{"slew", 5 * time.Microsecond, 15 * time.Microsecond},
+		{"step", 10 * time.Millisecond, 10 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &syntheticClock{
+				r:       rand.New(rand.NewSource(1)),
+				coeff:   0.5,
+				offset:  1_700_000_000_000_000_000,
+				counter: 1 << 40,
+			}
+			// Published conversion is 2ppm fast.
+			c.pubCoeff = c.coeff * (1 + 2e-6)
+			c.pubOffset = int64(float64(c.counter)*c.coeff) + c.offset + int64(tc.initialError) - int64(float64(c.counter)*c.pubCoeff)
+
+			d := newDisciplineForClock(c, DisciplineOptions{})
+
+			for i := range 300 {
+				if _, err := d.Update(); err != nil {
+					t.Fatal(err)
+				}
+
+				if e := c.error(); time.Duration(abs(e)) > tc.maxError {
+					t.Fatalf("error too big after %d updates: %dns", i+1, e)
+				}
+
+				if e := c.error(); float64(abs(e)) > c.boundBase+1 {
+					t.Fatalf("error bound should contain error after %d updates: %dns, bound: %.0fns", i+1, e, c.boundBase)
+				}
+			}
+
+			// Error bound shrinks with the uncertainty instead of widening since the initial calibration.
+			if c.boundBase > 200 || c.boundRate > 1e-7 {
+				t.Fatalf("error bound should converge, base: %.0fns, rate: %.3e", c.boundBase, c.boundRate)
+			}
+
+			if e := c.error(); abs(e) > 100 {
+				t.Fatalf("should converge, error: %dns", e)
+			}
+
+			if f := c.pubCoeff/c.coeff - 1; math.Abs(f) > 1e-8 {
+				t.Fatalf("frequency error should converge, got: %.3e", f)
+			}
+
+			s := d.State()
+			if s.OffsetStdDev > 100 || s.FrequencyStdDev > 1e-8 {
+				t.Fatalf("state should be certain, got: %+v", s)
+			}
+		})
+	}
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -0,0 +1,215 @@
+package tsc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Default configs of DriftModel.
+const (
+	defaultDriftSegment     = time.Second
+	defaultMaxExtrapolation = 10 * time.Minute
+	// driftPoints is the max number of the latest calibrations which the drift rate is fitted over.
+	driftPoints = 8
+	// minDriftPoints is the min number of calibrations for fitting the drift rate & telling its significance.
+	minDriftPoints = 3
+	// driftSignificance is the min |rate| in its standard errors, less significant rates are taken as no drift,
+	// because calibration noise in coeff is amplified by extrapolating.
+	driftSignificance = 3
+)
+
+// DriftOptions are options of DriftModel.
+// Zero value means default for each field.
+type DriftOptions struct {
+	// Segment is the length of linear segments which approximate the drifting conversion between calibrations.
+	// 1s by default.
+	Segment time.Duration
+	// MaxExtrapolation is how far after the latest calibration the drift is trusted,
+	// the frequency is held after that.
+	// 10min by default.
+	MaxExtrapolation time.Duration
+	// MinBaseline is the min interval between the calibrations which the drift rate is fitted over,
+	// the rate estimated from closer calibrations is too noisy for being extrapolated.
+	// MaxExtrapolation by default.
+	MinBaseline time.Duration
+	// Calibration is the options for calibrating.
+	Calibration CalibrationOptions
+}
+
+// DriftModel is an optional second-order model:
+// besides coeff & offset, it estimates the frequency drift rate by fitting the coeffs of the latest calibrations
+// (up to 8, at least 3 over MinBaseline), and extrapolates it between calibrations (within MaxExtrapolation)
+// by publishing piecewise-linear segments, which are continuous at segment boundaries.
+//
+// Crystals wander in frequency (e.g. by temperature), which a linear fit cannot capture between calibrations.
+// Call DriftModel.Calibrate instead of Calibrate periodically, and keep Run running in a goroutine.
+type DriftModel struct {
+	opts DriftOptions
+
+	mu           sync.Mutex
+	last         CalibrationResult // The latest calibration.
+	hasLast      bool
+	points       []CalibrationResult // The latest calibrations in ascending order of counter, for fitting rate.
+	rate         float64             // Change of coeff per counter tick, 0 means no (significant) drift.
+	extrapolated float64             // Coeff has been extrapolated to (0 means not yet).
+
+	// Hooks for testing.
+	calibrate func(CalibrationOptions) (CalibrationResult, error)
+	counter   func() int64
+	adjust    func(c int64, coeff float64, step int64)
+}
+
+// NewDriftModel creates a DriftModel with options.
+func NewDriftModel(opts DriftOptions) *DriftModel {
+	if opts.Segment <= 0 {
+		opts.Segment = defaultDriftSegment
+	}
+
+	if opts.MaxExtrapolation <= 0 {
+		opts.MaxExtrapolation = defaultMaxExtrapolation
+	}
+
+	if opts.MinBaseline <= 0 {
+		opts.MinBaseline = opts.MaxExtrapolation
+	}
+
+	return &DriftModel{
+		opts:      opts,
+		calibrate: CalibrateWithOptions,
+		counter:   RDTSC,
+		adjust:    slew,
+	}
+}
+
+// Calibrate calibrates with DriftOptions.Calibration, publishes the result,
+// and updates the drift rate by fitting the latest calibrations.
+func (m *DriftModel) Calibrate() (CalibrationResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := m.calibrate(m.opts.Calibration)
+	if err != nil {
+		return r, err
+	}
+
+	// Counter went backwards (e.g. reset by suspending or migrating VM), points before it are useless.
+	if m.hasLast && r.Counter <= m.last.Counter {
+		m.points = m.points[:0]
+	}
+
+	if len(m.points) == driftPoints {
+		m.points = append(m.points[:0], m.points[1:]...)
+	}
+
+	m.points = append(m.points, r)
+	m.rate = 0
+
+	if baseline := float64(r.Counter-m.points[0].Counter) * r.Coeff; baseline >= float64(m.opts.MinBaseline) {
+		m.rate = fitDriftRate(m.points)
+	}
+
+	m.last, m.hasLast = r, true
+	m.extrapolated = 0
+
+	return r, nil
+}
+
+// fitDriftRate fits the change of coeff per counter tick over points by least squares,
+// returns 0 if there are too few points or the rate isn't significant.
+func fitDriftRate(points []CalibrationResult) float64 {
+	n := len(points)
+	if n < minDriftPoints {
+		return 0
+	}
+
+	// Relative to the first point for keeping precision.
+	xs, ys, ws := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i, p := range points {
+		xs[i] = float64(p.Counter - points[0].Counter)
+		ys[i] = p.Coeff - points[0].Coeff
+		ws[i] = 1
+	}
+
+	a, b := weightedLinearRegression(xs, ys, ws)
+
+	var xmean float64
+	for _, x := range xs {
+		xmean += x / float64(n)
+	}
+
+	var ss, sxx float64
+	for i, x := range xs {
+		r := ys[i] - a - b*x
+		ss += r * r
+		sxx += (x - xmean) * (x - xmean)
+	}
+
+	// Standard error of the slope.
+	se := math.Sqrt(ss / float64(n-2) / sxx)
+	if math.Abs(b) < driftSignificance*se {
+		return 0
+	}
+
+	return b
+}
+
+// Rate returns the estimated frequency drift rate,
+// the relative change of frequency per second (e.g. 1e-9 means 1ppb/s).
+func (m *DriftModel) Rate() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.hasLast {
+		return 0
+	}
+
+	// Relative change of coeff per tick → per second, frequency changes in the opposite direction.
+	return -m.rate / m.last.Coeff * (1e9 / m.last.Coeff)
+}
+
+// Run extrapolates the drift every Segment until ctx is done.
+//
+// Returns ErrUnsupported if the counter is unsupported, or ctx.Err() after ctx is done.
+func (m *DriftModel) Run(ctx context.Context) error {
+	if !Supported() {
+		return ErrUnsupported
+	}
+
+	ticker := time.NewTicker(m.opts.Segment)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.extrapolate()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// extrapolate publishes the segment starting from now,
+// its coeff is the one extrapolated to the middle of the segment.
+func (m *DriftModel) extrapolate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.hasLast || m.rate == 0 {
+		return
+	}
+
+	c := m.counter()
+
+	limit := float64(m.opts.MaxExtrapolation) / m.last.Coeff
+	ticks := min(float64(c-m.last.Counter)+float64(m.opts.Segment)/m.last.Coeff/2, limit)
+
+	coeff := m.last.Coeff + m.rate*ticks
+	if coeff == m.extrapolated {
+		return // Held.
+	}
+
+	m.adjust(c, coeff, 0)
+	m.extrapolated = coeff
+}
@@ -0,0 +1,153 @@
+package tsc
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDriftModel(t *testing.T) {
+	t.Parallel()
+
+	const coeff = 0.5 // 2GHz.
+
+	m := NewDriftModel(DriftOptions{MaxExtrapolation: time.Minute})
+
+	var (
+		results   []CalibrationResult
+		counter   int64
+		published float64
+	)
+
+	m.calibrate = func(CalibrationOptions) (CalibrationResult, error) {
+		r := results[0]
+		results = results[1:]
+		published = r.Coeff
+
+		return r, nil
+	}
+	m.counter = func() int64 { return counter }
+	m.adjust = func(_ int64, coeff float64, _ int64) { published = coeff }
+
+	perMinute := int64(float64(time.Minute) / coeff)
+
+	// Coeff increases by 1e-7 per minute (frequency decreases by about 0.2ppm per minute).
+	results = []CalibrationResult{
+		{Coeff: coeff, Counter: 1 << 40},
+		{Coeff: coeff + 0.5e-7, Counter: 1<<40 + perMinute/2},
+		{Coeff: coeff + 1e-7, Counter: 1<<40 + perMinute},
+	}
+
+	for range results {
+		if _, err := m.Calibrate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if r := m.Rate(); math.Abs(r-(-1e-7/coeff/60)) > 1e-12 {
+		t.Fatalf("rate mismatch, got: %.3e", r)
+	}
+
+	last := int64(1<<40 + perMinute)
+	perSecond := int64(float64(time.Second) / coeff)
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		exp     float64
+	}{
+		// Extrapolated to the middle of the segment.
+		{30 * time.Second, coeff + 1e-7 + 1e-7*30.5/60},
+		// Held after MaxExtrapolation.
+		{2 * time.Minute, coeff + 2e-7},
+	} {
+		counter = last + int64(tc.elapsed.Seconds())*perSecond
+		m.extrapolate()
+
+		if math.Abs(published-tc.exp) > 1e-13 {
+			t.Fatalf("coeff mismatch after %s, exp: %.16f, got: %.16f", tc.elapsed, tc.exp, published)
+		}
+	}
+
+	// Counter goes backwards, the rate isn't fitted over calibrations before it.
+	results = []CalibrationResult{{Coeff: coeff, Counter: 1 << 30}}
+	if _, err := m.Calibrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := m.Rate(); r != 0 {
+		t.Fatalf("rate should be reset, got: %.3e", r)
+	}
+}
+
+func TestDriftModelNoisy(t *testing.T) {
+	t.Parallel()
+
+	const (
+		coeff = 0.5 // 2GHz.
+		// Calibration noise of coeff, about 2e-8 relative (the error of calibrating in 2s with 40ns residuals).
+		noise = 1e-8
+	)
+
+	perMinute := int64(float64(time.Minute) / coeff)
+
+	for _, tc := range []struct {
+		name  string
+		drift float64 // Change of coeff per minute.
+	}{
+		{"no drift", 0},
+		{"drift", 1e-7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := rand.New(rand.NewSource(1))
+			m := NewDriftModel(DriftOptions{})
+
+			var (
+				counter   int64
+				published float64
+				minutes   float64
+			)
+
+			truth := func(minutes float64) float64 { return coeff + tc.drift*minutes }
+
+			m.calibrate = func(CalibrationOptions) (CalibrationResult, error) {
+				c := CalibrationResult{Coeff: truth(minutes) + r.NormFloat64()*noise, Counter: counter}
+				published = c.Coeff
+
+				return c, nil
+			}
+			m.counter = func() int64 { return counter }
+			m.adjust = func(_ int64, coeff float64, _ int64) { published = coeff }
+
+			// Calibrates every 2 minutes, extrapolates for the next 2 minutes.
+			var worst float64
+
+			for i := range 32 {
+				minutes = float64(2 * i)
+				counter = 1<<40 + int64(minutes*float64(perMinute))
+
+				if _, err := m.Calibrate(); err != nil {
+					t.Fatal(err)
+				}
+
+				counter += 2 * perMinute
+				m.extrapolate()
+
+				if i >= driftPoints {
+					worst = max(worst, math.Abs(published-truth(minutes+2)))
+				}
+			}
+
+			// Noise isn't amplified by extrapolating, and drift is tracked.
+			if worst > 3*noise {
+				t.Fatalf("extrapolated coeff is too far from the truth: %.3e", worst)
+			}
+
+			if exp := -tc.drift / coeff / 60; math.Abs(m.Rate()-exp) > math.Abs(exp)*0.2+1e-10 {
+				t.Fatalf("rate mismatch, exp: %.3e, got: %.3e", exp, m.Rate())
+			}
+		})
+	}
+}
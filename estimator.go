@@ -0,0 +1,267 @@
+package tsc
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// ErrTooFewSamples is returned by Estimator when there aren't enough samples for estimating.
+var ErrTooFewSamples = errors.New("tsc: too few samples")
+
+// Sample is a pair of counter & reference clock values taken at (nearly) the same moment.
+type Sample struct {
+	Counter   int64 // Counter value.
+	Reference int64 // Reference clock value in nanoseconds.
+	// Uncertainty is the width of the counter bracket around the reference clock read (in counter ticks),
+	// the reference clock was read at some point in [Counter-Uncertainty/2, Counter+Uncertainty/2].
+	// 0 means unknown, such a sample is weighted as the narrowest bracket (one tick).
+	Uncertainty int64
+}
+
+// Estimate is the conversion estimated from samples:
+// reference = counter * Coeff + Offset.
+type Estimate struct {
+	Coeff  float64
+	Offset int64
+	// Error is the root-mean-square of residuals in nanoseconds.
+	Error float64
+	// Rejected is the number of samples rejected as outliers.
+	Rejected int
+}
+
+// Estimator estimates the conversion from counter to reference clock.
+//
+// Implementations must not retain samples after Estimate returns.
+type Estimator interface {
+	Estimate(samples []Sample) (Estimate, error)
+}
+
+// OLS is the ordinary least squares (simple linear regression with intercept) Estimator.
+// It's the default Estimator.
+//
+// Samples are weighted by the inverse square of their uncertainties,
+// an unknown (0) uncertainty is taken as one tick, which is the max weight.
+// So if unknown & known uncertainties are mixed, the samples with unknown ones are weighted highest.
+type OLS struct{}
+
+// Estimate implements Estimator.
+func (OLS) Estimate(samples []Sample) (Estimate, error) {
+	if len(samples) < 2 {
+		return Estimate{}, ErrTooFewSamples
+	}
+
+	xs, ys := centerSamples(samples)
+	a, coeff := weightedLinearRegression(xs, ys, sampleWeights(samples))
+	offset := uncenterOffset(samples[0], a, coeff)
+
+	return Estimate{
+		Coeff:  coeff,
+		Offset: offset,
+		Error:  residualRMS(samples, coeff, offset),
+	}, nil
+}
+
+// residualRMS returns the root-mean-square of residuals in nanoseconds.
+func residualRMS(samples []Sample, coeff float64, offset int64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	sum := float64(0)
+
+	for _, s := range samples {
+		r := float64(residual(s, coeff, offset))
+		sum += r * r
+	}
+
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// residual returns reference - predicted in nanoseconds,
+// predicted is calculated in the same way as the fast path.
+func residual(s Sample, coeff float64, offset int64) int64 {
+	return s.Reference - (int64(float64(s.Counter)*coeff) + offset)
+}
+
+// Default configs of Robust.
+const (
+	defaultRobustThreshold  = 3.5
+	defaultRobustIterations = 8
+	// huberK is the tuning constant of Huber weights, 95% efficiency on normal distribution.
+	huberK = 1.345
+	// madScale makes MAD a consistent estimator of standard deviation on normal distribution.
+	madScale = 1.4826
+)
+
+// Robust is an outlier-robust Estimator.
+//
+// A single sample taken during a preemption or an SMI skews OLS a lot.
+// Robust fits by iteratively reweighted least squares with Huber weights first,
+// then rejects samples whose residual is bigger than Threshold robust standard deviations
+// (estimated by MAD of residuals), and refits on the rest.
+type Robust struct {
+	// Threshold is the max residual of inliers in robust standard deviations.
+	// 3.5 by default.
+	Threshold float64
+	// Iterations is the max number of IRLS iterations.
+	// 8 by default.
+	Iterations int
+}
+
+// Estimate implements Estimator.
+func (r Robust) Estimate(samples []Sample) (Estimate, error) {
+	if len(samples) < 2 {
+		return Estimate{}, ErrTooFewSamples
+	}
+
+	threshold := r.Threshold
+	if threshold <= 0 {
+		threshold = defaultRobustThreshold
+	}
+
+	iterations := r.Iterations
+	if iterations <= 0 {
+		iterations = defaultRobustIterations
+	}
+
+	xs, ys := centerSamples(samples)
+	base := sampleWeights(samples)
+
+	ws := slices.Clone(base)
+	a, b := weightedLinearRegression(xs, ys, ws)
+	res := make([]float64, len(samples))
+
+	for range iterations {
+		sigma := residualSigma(xs, ys, a, b, res)
+		if sigma == 0 {
+			break
+		}
+
+		for i, v := range res {
+			ws[i] = base[i]
+			if v = math.Abs(v); v > huberK*sigma {
+				ws[i] = base[i] * huberK * sigma / v
+			}
+		}
+
+		a, b = weightedLinearRegression(xs, ys, ws)
+	}
+
+	// Rejects outliers by residuals of the robust fit, and refits on inliers.
+	sigma := residualSigma(xs, ys, a, b, res)
+	inliers := make([]Sample, 0, len(samples))
+
+	for i, v := range res {
+		if sigma == 0 || math.Abs(v) <= threshold*sigma {
+			inliers = append(inliers, samples[i])
+		}
+	}
+
+	if len(inliers) < 2 {
+		return Estimate{}, ErrTooFewSamples
+	}
+
+	xs, ys = centerSamples(inliers)
+	a, b = weightedLinearRegression(xs, ys, sampleWeights(inliers))
+	offset := uncenterOffset(inliers[0], a, b)
+
+	return Estimate{
+		Coeff:    b,
+		Offset:   offset,
+		Error:    residualRMS(inliers, b, offset),
+		Rejected: len(samples) - len(inliers),
+	}, nil
+}
+
+// centerSamples converts samples to float64 relative to the first sample,
+// which keeps precision (float64 has only 53 bits, but Unix nanoseconds need 61 bits).
+func centerSamples(samples []Sample) (xs, ys []float64) {
+	xs = make([]float64, len(samples))
+	ys = make([]float64, len(samples))
+
+	for i, s := range samples {
+		xs[i] = float64(s.Counter - samples[0].Counter)
+		ys[i] = float64(s.Reference - samples[0].Reference)
+	}
+
+	return xs, ys
+}
+
+// uncenterOffset converts intercept a (relative to origin) to the offset used by the fast path.
+func uncenterOffset(origin Sample, a, b float64) int64 {
+	return origin.Reference + int64(math.Round(a-b*float64(origin.Counter)))
+}
+
+// sampleWeights returns the weights of samples by sampleWeight.
+func sampleWeights(samples []Sample) []float64 {
+	ws := make([]float64, len(samples))
+
+	for i, s := range samples {
+		ws[i] = sampleWeight(s)
+	}
+
+	return ws
+}
+
+// sampleWeight returns the inverse square of the uncertainty of s as its weight,
+// unknown uncertainty is taken as one tick (the narrowest bracket, see sampler.sample).
+// Batch estimators & Online share it, so they weight samples in the same way.
+func sampleWeight(s Sample) float64 {
+	u := float64(max(s.Uncertainty, 1))
+
+	return 1 / (u * u)
+}
+
+// weightedLinearRegression fits y = a + b*x by weighted least squares.
+func weightedLinearRegression(xs, ys, ws []float64) (a, b float64) {
+	sw, xmean, ymean := float64(0), float64(0), float64(0)
+	for i := range xs {
+		sw += ws[i]
+		xmean += ws[i] * xs[i]
+		ymean += ws[i] * ys[i]
+	}
+
+	xmean /= sw
+	ymean /= sw
+
+	denominator, numerator := float64(0), float64(0)
+	for i := range xs {
+		numerator += ws[i] * (xs[i] - xmean) * (ys[i] - ymean)
+		denominator += ws[i] * (xs[i] - xmean) * (xs[i] - xmean)
+	}
+
+	b = numerator / denominator
+
+	return ymean - b*xmean, b
+}
+
+// residualSigma fills residuals of y = a + b*x into res,
+// and returns the robust standard deviation of them (scaled MAD).
+func residualSigma(xs, ys []float64, a, b float64, res []float64) float64 {
+	abs := make([]float64, len(xs))
+
+	for i := range xs {
+		res[i] = ys[i] - (a + b*xs[i])
+		abs[i] = res[i]
+	}
+
+	med := median(abs)
+	for i := range abs {
+		abs[i] = math.Abs(abs[i] - med)
+	}
+
+	return madScale * median(abs)
+}
+
+// median returns the median of vs, vs will be sorted.
+func median(vs []float64) float64 {
+	slices.Sort(vs)
+
+	n := len(vs)
+	if isEven(n) {
+		return (vs[n/2-1] + vs[n/2]) / 2
+	}
+
+	return vs[n/2]
+}
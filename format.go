@@ -0,0 +1,250 @@
+package tsc
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// dateLen is the length of the date & time part (2006-01-02T15:04:05).
+const dateLen = len("2006-01-02T15:04:05")
+
+// dateCache caches the date & time part of the latest rendered second,
+// timestamps in the same second only render the sub-second digits.
+//
+// It's a sequence lock made of atomics, so it's shared by goroutines without allocating or blocking:
+// writers make seq odd while writing (and give up if another writer holds it),
+// readers render by themselves instead of retrying if seq changed.
+var dateCache struct {
+	seq  atomic.Uint64
+	sec  atomic.Int64 // Local seconds (Unix seconds + offset) of date.
+	date [3]atomic.Uint64
+}
+
+func init() {
+	var b [24]byte
+
+	renderDate(&b, 0)
+	storeDate(0, &b)
+}
+
+// AppendRFC3339Nano appends Unix nanoseconds ns in RFC 3339 with nanoseconds in UTC to dst,
+// it's the same as time.Unix(0, ns).UTC().AppendFormat(dst, time.RFC3339Nano) (trailing zeros are removed),
+// e.g. 2006-01-02T15:04:05.999999999Z.
+//
+// It doesn't allocate if dst has enough capacity (35 bytes at most).
+func AppendRFC3339Nano(dst []byte, ns int64) []byte {
+	sec, nsec := splitUnixNano(ns)
+
+	dst = appendDate(dst, sec)
+
+	if nsec != 0 {
+		var b [10]byte
+
+		b[0] = '.'
+		putDigits(b[1:], uint64(nsec))
+
+		n := len(b)
+		for b[n-1] == '0' {
+			n--
+		}
+
+		dst = append(dst, b[:n]...)
+	}
+
+	return append(dst, 'Z')
+}
+
+// AppendRFC3339Micro appends Unix nanoseconds ns in RFC 3339 with fixed 6 fractional digits in UTC to dst,
+// it's the same as time.Unix(0, ns).UTC().AppendFormat(dst, "2006-01-02T15:04:05.000000Z07:00"),
+// e.g. 2006-01-02T15:04:05.999999Z.
+//
+// It doesn't allocate if dst has enough capacity (27 bytes).
+func AppendRFC3339Micro(dst []byte, ns int64) []byte {
+	sec, nsec := splitUnixNano(ns)
+
+	var b [8]byte
+
+	b[0] = '.'
+	putDigits(b[1:7], uint64(nsec/1000))
+	b[7] = 'Z'
+
+	return append(appendDate(dst, sec), b[:]...)
+}
+
+// AppendISO8601 appends Unix nanoseconds ns in ISO 8601 with fixed 9 fractional digits
+// at the fixed offset (seconds east of UTC) to dst,
+// it's the same as time.Unix(0, ns).In(time.FixedZone("", offset)).AppendFormat(dst, "2006-01-02T15:04:05.000000000-07:00"),
+// e.g. 2006-01-02T15:04:05.999999999-07:00. The offset is rendered as ±hh:mm even if it's 0.
+//
+// It doesn't allocate if dst has enough capacity (35 bytes).
+func AppendISO8601(dst []byte, ns int64, offset int) []byte {
+	sec, nsec := splitUnixNano(ns)
+
+	var b [16]byte
+
+	b[0] = '.'
+	putDigits(b[1:10], uint64(nsec))
+
+	// Offset is truncated to minutes as time.Format does.
+	zone := offset / 60
+
+	b[10] = '+'
+	if zone < 0 {
+		b[10] = '-'
+		zone = -zone
+	}
+
+	putDigits(b[11:13], uint64(zone/60))
+	b[13] = ':'
+	putDigits(b[14:16], uint64(zone%60))
+
+	return append(appendDate(dst, sec+int64(offset)), b[:]...)
+}
+
+// AppendEpoch appends Unix nanoseconds ns as Unix seconds with fixed 9 fractional digits to dst,
+// e.g. 1136214245.999999999 (or -0.000000001 for ns = -1).
+//
+// It doesn't allocate if dst has enough capacity (21 bytes at most).
+func AppendEpoch(dst []byte, ns int64) []byte {
+	// Absolute value in uint64 for avoiding overflow of -math.MinInt64.
+	u := uint64(ns)
+	if ns < 0 {
+		dst = append(dst, '-')
+		u = uint64(-ns)
+	}
+
+	var b [20]byte
+
+	i := len(b) - 10
+	b[i] = '.'
+	putDigits(b[i+1:], u%1e9)
+
+	sec := u / 1e9
+	for {
+		i--
+		b[i] = byte('0' + sec%10)
+
+		sec /= 10
+		if sec == 0 {
+			break
+		}
+	}
+
+	return append(dst, b[i:]...)
+}
+
+// splitUnixNano splits Unix nanoseconds to seconds and nanoseconds in [0, 1e9).
+func splitUnixNano(ns int64) (sec int64, nsec int32) {
+	sec = floorDiv(ns, 1e9)
+
+	return sec, int32(ns - sec*1e9)
+}
+
+// appendDate appends the date & time part of local seconds to dst by dateCache.
+func appendDate(dst []byte, local int64) []byte {
+	var b [24]byte
+
+	if seq := dateCache.seq.Load(); seq&1 == 0 && dateCache.sec.Load() == local {
+		for i := range dateCache.date {
+			binary.LittleEndian.PutUint64(b[i*8:], dateCache.date[i].Load())
+		}
+
+		if dateCache.seq.Load() == seq {
+			return append(dst, b[:dateLen]...)
+		}
+	}
+
+	renderDate(&b, local)
+	storeDate(local, &b)
+
+	return append(dst, b[:dateLen]...)
+}
+
+// storeDate stores the rendered date of local seconds into dateCache,
+// it gives up if another writer is storing.
+func storeDate(local int64, b *[24]byte) {
+	seq := dateCache.seq.Load()
+	if seq&1 != 0 || !dateCache.seq.CompareAndSwap(seq, seq+1) {
+		return
+	}
+
+	dateCache.sec.Store(local)
+
+	for i := range dateCache.date {
+		dateCache.date[i].Store(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+
+	dateCache.seq.Store(seq + 2)
+}
+
+// renderDate renders the date & time part of local seconds into b[:dateLen].
+func renderDate(b *[24]byte, local int64) {
+	days := floorDiv(local, 86400)
+	secs := local - days*86400
+
+	year, month, day := civilFromDays(days)
+
+	putDigits(b[0:4], uint64(year))
+	b[4] = '-'
+	putDigits(b[5:7], uint64(month))
+	b[7] = '-'
+	putDigits(b[8:10], uint64(day))
+	b[10] = 'T'
+	putDigits(b[11:13], uint64(secs/3600))
+	b[13] = ':'
+	putDigits(b[14:16], uint64(secs/60%60))
+	b[16] = ':'
+	putDigits(b[17:19], uint64(secs%60))
+}
+
+// civilFromDays converts days since 1970-01-01 to proleptic Gregorian date,
+// see http://howardhinnant.github.io/date_algorithms.html#civil_from_days.
+func civilFromDays(days int64) (year, month, day int64) {
+	days += 719468
+
+	era := floorDiv(days, 146097)
+	doe := days - era*146097                               // [0, 146096]
+	yoe := (doe - doe/1460 + doe/36524 - doe/146096) / 365 // [0, 399]
+	doy := doe - (365*yoe + yoe/4 - yoe/100)               // [0, 365]
+	mp := (5*doy + 2) / 153                                // [0, 11], March is 0.
+
+	day = doy - (153*mp+2)/5 + 1
+	month = mp + 3
+	if month > 12 {
+		month -= 12
+	}
+
+	year = yoe + era*400
+	if month <= 2 {
+		year++
+	}
+
+	return year, month, day
+}
+
+// digitPairs is decimal digits of 00 to 99.
+const digitPairs = "00010203040506070809" +
+	"10111213141516171819" +
+	"20212223242526272829" +
+	"30313233343536373839" +
+	"40414243444546474849" +
+	"50515253545556575859" +
+	"60616263646566676869" +
+	"70717273747576777879" +
+	"80818283848586878889" +
+	"90919293949596979899"
+
+// putDigits puts v in decimal into b with leading zeros, higher digits beyond len(b) are dropped.
+// Two digits are put at a time.
+func putDigits(b []byte, v uint64) {
+	i := len(b)
+	for ; i >= 2; i -= 2 {
+		r := v % 100 * 2
+		b[i-2], b[i-1] = digitPairs[r], digitPairs[r+1]
+		v /= 100
+	}
+
+	if i == 1 {
+		b[0] = byte('0' + v%10)
+	}
+}
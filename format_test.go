@@ -0,0 +1,233 @@
+package tsc
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func formatTestNanos() []int64 {
+	rng := rand.New(rand.NewPCG(3, 4))
+
+	ns := []int64{
+		0, 1, -1, 999_999_999, 1_000_000_000, -1_000_000_000, 1_000_000,
+		math.MaxInt64, math.MinInt64,
+		time.Date(2000, 2, 29, 23, 59, 59, 999_999_999, time.UTC).UnixNano(),
+		time.Date(2100, 3, 1, 0, 0, 0, 0, time.UTC).UnixNano(),
+		time.Date(1900, 2, 28, 12, 0, 0, 120_000_000, time.UTC).UnixNano(),
+	}
+
+	for range 10000 {
+		ns = append(ns, int64(rng.Uint64()))
+	}
+
+	// Same seconds, for hitting the cache.
+	base := time.Now().UnixNano()
+	for i := range int64(1000) {
+		ns = append(ns, base+i*997)
+	}
+
+	return ns
+}
+
+func TestAppendRFC3339(t *testing.T) {
+	t.Parallel()
+
+	const micro = "2006-01-02T15:04:05.000000Z07:00"
+
+	for _, ns := range formatTestNanos() {
+		tm := time.Unix(0, ns).UTC()
+
+		if got, exp := string(AppendRFC3339Nano(nil, ns)), tm.Format(time.RFC3339Nano); got != exp {
+			t.Fatalf("rfc3339 nano mismatch at %d: exp: %s, got: %s", ns, exp, got)
+		}
+
+		if got, exp := string(AppendRFC3339Micro(nil, ns)), tm.Format(micro); got != exp {
+			t.Fatalf("rfc3339 micro mismatch at %d: exp: %s, got: %s", ns, exp, got)
+		}
+	}
+}
+
+func TestAppendISO8601(t *testing.T) {
+	t.Parallel()
+
+	const layout = "2006-01-02T15:04:05.000000000-07:00"
+
+	for _, offset := range []int{0, 8 * 3600, -7 * 3600, 5*3600 + 45*60, -(9*3600 + 30*60), 14 * 3600, -12 * 3600, 90, -90} {
+		loc := time.FixedZone("", offset)
+
+		for _, ns := range formatTestNanos() {
+			got, exp := string(AppendISO8601(nil, ns, offset)), time.Unix(0, ns).In(loc).Format(layout)
+			if got != exp {
+				t.Fatalf("iso8601 mismatch at %d, offset %d: exp: %s, got: %s", ns, offset, exp, got)
+			}
+		}
+	}
+}
+
+func TestAppendEpoch(t *testing.T) {
+	t.Parallel()
+
+	exps := map[int64]string{
+		0:             "0.000000000",
+		-1:            "-0.000000001",
+		1_500_000_000: "1.500000000",
+		math.MaxInt64: "9223372036.854775807",
+		math.MinInt64: "-9223372036.854775808",
+	}
+
+	for ns, exp := range exps {
+		if got := string(AppendEpoch(nil, ns)); got != exp {
+			t.Fatalf("epoch mismatch at %d: exp: %s, got: %s", ns, exp, got)
+		}
+	}
+
+	for _, ns := range formatTestNanos() {
+		got := string(AppendEpoch(nil, ns))
+
+		// Parses seconds & fraction back.
+		sec, frac, _ := strings.Cut(got, ".")
+
+		s, err := strconv.ParseInt(sec, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := strconv.ParseInt(frac, 10, 64)
+		if err != nil || len(frac) != 9 {
+			t.Fatalf("invalid fraction: %s", got)
+		}
+
+		if ns < 0 {
+			f = -f
+		}
+
+		if s*1e9+f != ns {
+			t.Fatalf("epoch mismatch at %d: got: %s", ns, got)
+		}
+	}
+}
+
+//nolint:paralleltest // AllocsPerRun panics in parallel tests.
+func TestAppendAllocs(t *testing.T) {
+	if raceDetectorEnabled {
+		t.Skip("race detector allocates")
+	}
+
+	dst := make([]byte, 0, 64)
+	ns := time.Now().UnixNano()
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		ns += 1_000_003 // Crosses seconds sometimes.
+		dst = AppendRFC3339Nano(dst[:0], ns)
+		dst = AppendRFC3339Micro(dst[:0], ns)
+		dst = AppendISO8601(dst[:0], ns, 8*3600)
+		dst = AppendEpoch(dst[:0], ns)
+	})
+	if allocs != 0 {
+		t.Fatalf("should not allocate, got: %f allocs", allocs)
+	}
+}
+
+func TestAppendConcurrently(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+
+	for g := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			dst := make([]byte, 0, 64)
+			// Goroutines render different seconds for racing on the cache.
+			ns := time.Now().UnixNano() + int64(g)*int64(time.Second)
+
+			for i := range int64(10000) {
+				ns := ns + i*int64(time.Millisecond)
+
+				dst = AppendRFC3339Nano(dst[:0], ns)
+				if exp := time.Unix(0, ns).UTC().Format(time.RFC3339Nano); string(dst) != exp {
+					t.Errorf("rfc3339 nano mismatch at %d: exp: %s, got: %s", ns, exp, dst)
+
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkAppendRFC3339Nano(b *testing.B) {
+	dst := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		dst = AppendRFC3339Nano(dst[:0], UnixNano())
+	}
+}
+
+func BenchmarkTimeFormatRFC3339Nano(b *testing.B) {
+	b.ReportAllocs()
+
+	for range b.N {
+		_ = time.Unix(0, UnixNano()).UTC().Format(time.RFC3339Nano)
+	}
+}
+
+func BenchmarkTimeAppendFormatRFC3339Nano(b *testing.B) {
+	dst := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		dst = time.Unix(0, UnixNano()).UTC().AppendFormat(dst[:0], time.RFC3339Nano)
+	}
+}
+
+func BenchmarkAppendRFC3339Micro(b *testing.B) {
+	dst := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		dst = AppendRFC3339Micro(dst[:0], UnixNano())
+	}
+}
+
+func BenchmarkAppendISO8601(b *testing.B) {
+	dst := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		dst = AppendISO8601(dst[:0], UnixNano(), 8*3600)
+	}
+}
+
+func BenchmarkTimeFormatISO8601(b *testing.B) {
+	loc := time.FixedZone("", 8*3600)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		_ = time.Unix(0, UnixNano()).In(loc).Format("2006-01-02T15:04:05.000000000-07:00")
+	}
+}
+
+func BenchmarkAppendEpoch(b *testing.B) {
+	dst := make([]byte, 0, 64)
+
+	b.ReportAllocs()
+
+	for range b.N {
+		dst = AppendEpoch(dst[:0], UnixNano())
+	}
+}
@@ -0,0 +1,102 @@
+package tsc
+
+import (
+	"sort"
+	"sync"
+)
+
+// historySize is the max number of calibration points kept in history,
+// it's about 5 hours if calibrating every 5 minutes.
+const historySize = 64
+
+// calibrationPoint is where a calibration measured the conversion.
+type calibrationPoint struct {
+	counter int64 // Counter value at the middle of samples.
+	ns      int64 // Unix nanoseconds of counter by the calibration.
+	coeff   float64
+	offset  int64
+}
+
+// calibrationHistory is a bounded history of calibration points in ascending order of counter.
+type calibrationHistory struct {
+	mu     sync.RWMutex
+	points []calibrationPoint
+}
+
+var history calibrationHistory
+
+// record records a calibration point, the oldest one is evicted if history is full.
+func (h *calibrationHistory) record(counter int64, coeff float64, offset int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Counter went backwards (e.g. reset by suspending or migrating VM), points before it are useless.
+	if n := len(h.points); n > 0 && counter <= h.points[n-1].counter {
+		h.points = h.points[:0]
+	}
+
+	if len(h.points) == historySize {
+		copy(h.points, h.points[1:])
+		h.points = h.points[:historySize-1]
+	}
+
+	h.points = append(h.points, calibrationPoint{
+		counter: counter,
+		ns:      int64(float64(counter)*coeff) + offset,
+		coeff:   coeff,
+		offset:  offset,
+	})
+}
+
+// convert converts cycles by history,
+// returns false if history is empty or cycles is newer than the latest point.
+func (h *calibrationHistory) convert(cycles int64) (int64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ps := h.points
+	if len(ps) == 0 {
+		return 0, false
+	}
+
+	// i is the first point after cycles.
+	i := sort.Search(len(ps), func(i int) bool { return ps[i].counter > cycles })
+
+	switch i {
+	case 0:
+		return int64(float64(cycles)*ps[0].coeff) + ps[0].offset, true
+	case len(ps):
+		// The published conversion may have been corrected (e.g. by Discipline) since the latest calibration.
+		return 0, false
+	}
+
+	// Interpolates between the surrounding calibrations,
+	// which captures the frequency drift between them.
+	p0, p1 := ps[i-1], ps[i]
+	slope := float64(p1.ns-p0.ns) / float64(p1.counter-p0.counter)
+
+	return p0.ns + int64(float64(cycles-p0.counter)*slope), true
+}
+
+// ConvertHistorical converts a counter value (e.g. recorded by RDTSC on hot paths) to Unix nanoseconds,
+// by interpolating between the calibrations before & after it in history.
+//
+// Calibrations made after the counter was recorded make the result more accurate than
+// what UnixNano returned at that time, because the frequency drift between calibrations is captured.
+// Counter values newer than the latest calibration are converted by the conversion in effect now
+// (see CurrentSnapshot), which includes corrections made by Discipline, Online or DriftModel since then,
+// and the ones older than history are converted by the oldest calibration in history.
+//
+// History keeps the latest 64 calibrations (by Calibrate, CalibrateWithOptions...).
+// If the counter is unsupported, it returns cycles (see Ticks).
+func ConvertHistorical(cycles int64) int64 {
+	if !Supported() {
+		return cycles
+	}
+
+	if ns, ok := history.convert(cycles); ok {
+		return ns
+	}
+
+	return CurrentSnapshot().ToUnixNano(cycles)
+}
@@ -0,0 +1,121 @@
+package tsc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalibrationHistory(t *testing.T) {
+	t.Parallel()
+
+	// True conversion drifts: coeff changes by 1e-7 (relative 2e-7) per calibration interval.
+	const (
+		coeff0   = 0.5
+		drift    = 1e-7
+		interval = int64(600_000_000_000) // 5min at 2GHz.
+		start    = int64(1 << 40)
+	)
+
+	truth := func(c int64) int64 {
+		x := float64(c - start)
+		k := drift / float64(interval)
+
+		return 1_700_000_000_000_000_000 + int64(x*coeff0+k*x*x/2)
+	}
+
+	slopeAt := func(c int64) float64 {
+		return coeff0 + drift*float64(c-start)/float64(interval)
+	}
+
+	var h calibrationHistory
+
+	if _, ok := h.convert(start); ok {
+		t.Fatal("empty history shouldn't convert")
+	}
+
+	for i := range historySize + 8 {
+		c := start + int64(i)*interval
+		coeff := slopeAt(c)
+		h.record(c, coeff, truth(c)-int64(float64(c)*coeff))
+	}
+
+	if len(h.points) != historySize {
+		t.Fatalf("history should be bounded, got: %d points", len(h.points))
+	}
+
+	// Counters between two calibrations, the live conversion is the one before them.
+	var liveErr, histErr int64
+
+	for i := 8; i < historySize+7; i++ {
+		for _, frac := range []float64{0.25, 0.5, 0.75, 0.95} {
+			c := start + int64(i)*interval + int64(frac*float64(interval))
+			exp := truth(c)
+
+			p := h.points[i-8]
+			live := int64(float64(c)*p.coeff) + p.offset
+
+			got, ok := h.convert(c)
+			if !ok {
+				t.Fatal("should convert")
+			}
+
+			liveErr = max(liveErr, abs(live-exp))
+			histErr = max(histErr, abs(got-exp))
+		}
+	}
+
+	if histErr*3 > liveErr {
+		t.Fatalf("history should be much more accurate than live, live: %dns, history: %dns", liveErr, histErr)
+	}
+
+	// Counters newer than history are left to the live conversion.
+	if _, ok := h.convert(h.points[len(h.points)-1].counter + 1); ok {
+		t.Fatal("counters newer than history shouldn't be converted by history")
+	}
+
+	// Counter goes backwards, history is reset.
+	h.record(start, coeff0, 0)
+
+	if len(h.points) != 1 {
+		t.Fatalf("history should be reset, got: %d points", len(h.points))
+	}
+}
+
+func TestConvertHistorical(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	c := GetInOrder()
+	ns := UnixNano()
+
+	if d := ConvertHistorical(c) - ns; d > 10_000 || d < -10_000 {
+		t.Fatalf("should be close to UnixNano, delta: %dns", d)
+	}
+}
+
+//nolint:paralleltest // Slewing changes global parameters.
+func TestConvertHistoricalAfterSlew(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	if _, err := CalibrateWithOptions(CalibrationOptions{Samples: 16, Interval: 4 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer Calibrate()
+
+	// Corrected as Discipline does after the latest calibration.
+	_, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	slew(GetInOrder(), coeff*(1+1e-5), int64(time.Millisecond))
+
+	c := GetInOrder()
+	ns := UnixNano()
+
+	if d := ConvertHistorical(c) - ns; d > 10_000 || d < -10_000 {
+		t.Fatalf("should follow corrections after the latest calibration, delta: %dns", d)
+	}
+}
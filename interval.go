@@ -0,0 +1,115 @@
+package tsc
+
+import (
+	"math"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Configs of error bound.
+const (
+	// errorSigmas is the number of standard deviations (RMS residuals) in the error bound.
+	errorSigmas = 3
+	// defaultDriftRate is the min drift rate between the counter & reference clock,
+	// it's about the frequency wander of common crystals in minutes.
+	// The drift rate is raised by the frequency change between the latest two calibrations,
+	// or by the frequency uncertainty estimated by Discipline.
+	defaultDriftRate = 1e-6
+	// commitWaitSpin is the time left in CommitWait for spinning instead of sleeping.
+	commitWaitSpin = 50 * time.Microsecond
+)
+
+// errorBound is the error bound of UnixNano by the latest publish.
+type errorBound struct {
+	at    int64   // Reference clock at publish in nanoseconds.
+	coeff float64 // Published coeff.
+	base  float64 // Error bound at publish in nanoseconds.
+	rate  float64 // Drift rate (ns/ns) since publish.
+
+	// calibrated is the coeff of the latest calibration,
+	// Discipline & Online keep it, so the drift rate of the next calibration isn't got from a slewed coeff.
+	calibrated float64
+}
+
+// bound is nil before the first publish.
+var bound atomic.Pointer[errorBound]
+
+// updateErrorBound updates the error bound by a calibration at reference clock at,
+// the error bound is made of the residuals (RMS in nanoseconds) and the median bracket width of samples.
+func updateErrorBound(at int64, coeff, rms float64, samples []Sample) {
+	brackets := make([]float64, len(samples))
+	for i, s := range samples {
+		brackets[i] = float64(s.Uncertainty)
+	}
+
+	b := &errorBound{
+		at:    at,
+		coeff: coeff,
+		base:  errorSigmas*rms + median(brackets)*coeff/2,
+		rate:  defaultDriftRate,
+
+		calibrated: coeff,
+	}
+
+	if prev := bound.Load(); prev != nil && prev.calibrated > 0 {
+		b.rate = max(b.rate, math.Abs(coeff/prev.calibrated-1))
+	}
+
+	bound.Store(b)
+}
+
+// setErrorBound sets the error bound estimated by Discipline or Online at reference clock at,
+// the drift rate is at least defaultDriftRate.
+func setErrorBound(at int64, coeff, base, rate float64) {
+	b := &errorBound{
+		at:    at,
+		coeff: coeff,
+		base:  base,
+		rate:  max(rate, defaultDriftRate),
+	}
+
+	if prev := bound.Load(); prev != nil {
+		b.calibrated = prev.calibrated
+	}
+
+	bound.Store(b)
+}
+
+// NowInterval returns an interval which contains the true time (by reference clock) as UnixNano,
+// its width is derived from the error estimated by the latest publish,
+// plus the time since publish multiplied by the estimated drift rate.
+//
+// It's updated by calibrations (Calibrate, CalibrateWithOptions...) with the residuals & the bracket width of samples,
+// by Discipline with the uncertainty of its Kalman filter, and by Online with the error of its estimate.
+// Returns an interval of zero width if there is no calibration (e.g. unsupported).
+func NowInterval() (earliest, latest int64) {
+	ns := UnixNano()
+
+	b := bound.Load()
+	if b == nil {
+		return ns, ns
+	}
+
+	half := b.base + math.Abs(float64(ns-b.at))*b.rate
+	d := int64(math.Ceil(half))
+
+	return ns - d, ns + d
+}
+
+// CommitWait blocks until earliest of NowInterval is after ts,
+// which means ts has been passed for sure (on any node using the same reference clock).
+func CommitWait(ts int64) {
+	for {
+		earliest, _ := NowInterval()
+		if earliest > ts {
+			return
+		}
+
+		if left := time.Duration(ts - earliest); left > commitWaitSpin {
+			time.Sleep(left - commitWaitSpin)
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tsc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNowInterval(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	for range 1000 {
+		t0 := time.Now().UnixNano()
+		earliest, latest := NowInterval()
+		t1 := time.Now().UnixNano()
+
+		if earliest > latest {
+			t.Fatalf("invalid interval: [%d, %d]", earliest, latest)
+		}
+
+		if earliest > t1 || latest < t0 {
+			t.Fatalf("interval [%d, %d] doesn't contain the system clock [%d, %d]", earliest, latest, t0, t1)
+		}
+	}
+
+	earliest, latest := NowInterval()
+	t.Logf("width: %dns", latest-earliest)
+}
+
+func TestCommitWait(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	_, ts := NowInterval()
+
+	start := time.Now()
+
+	CommitWait(ts)
+
+	if earliest, _ := NowInterval(); earliest <= ts {
+		t.Fatalf("earliest %d should be after %d", earliest, ts)
+	}
+
+	if now := time.Now().UnixNano(); now <= ts {
+		t.Fatalf("system clock %d should be after %d", now, ts)
+	}
+
+	t.Logf("waited: %s", time.Since(start))
+}
+
+//nolint:paralleltest // Discipline changes the global error bound & parameters.
+func TestNowIntervalByDiscipline(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	defer Calibrate()
+
+	// Error bound set long ago keeps widening by the drift rate.
+	prev := bound.Load()
+	bound.Store(&errorBound{at: time.Now().Add(-time.Hour).UnixNano(), coeff: prev.coeff, base: prev.base, rate: prev.rate, calibrated: prev.calibrated})
+
+	d := NewDiscipline(DisciplineOptions{})
+
+	for range 4 {
+		if _, err := d.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := bound.Load()
+	if b.calibrated != prev.calibrated {
+		t.Fatalf("calibrated coeff should be kept, exp: %.16f, got: %.16f", prev.calibrated, b.calibrated)
+	}
+
+	if since := time.Duration(time.Now().UnixNano() - b.at); since > time.Second {
+		t.Fatalf("error bound should be updated by Discipline, set %s ago", since)
+	}
+
+	t0 := time.Now().UnixNano()
+	earliest, latest := NowInterval()
+	t1 := time.Now().UnixNano()
+
+	if earliest > t1 || latest < t0 {
+		t.Fatalf("interval [%d, %d] doesn't contain the system clock [%d, %d]", earliest, latest, t0, t1)
+	}
+
+	// 1 hour drift at 1ppm is 3.6ms.
+	if w := time.Duration(latest - earliest); w > time.Millisecond {
+		t.Fatalf("interval should be narrowed by Discipline, width: %s", w)
+	}
+}
@@ -0,0 +1,274 @@
+package tsc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // Required by the leap-seconds.list format, not for security.
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Epochs of time scales.
+const (
+	// ntpEpochOffset is seconds from NTP epoch (1900-01-01) to Unix epoch.
+	ntpEpochOffset = 2208988800
+	// gpsEpoch is the Unix time of GPS epoch (1980-01-06 00:00:00 UTC).
+	gpsEpoch = 315964800
+	// gpsTAIOffset is TAI - GPS in seconds, it's constant.
+	gpsTAIOffset = 19
+)
+
+// embeddedLeapSeconds is leap-seconds.list published by IERS, it must be updated before it expires
+// (by `just update-leap-seconds`, TestCheckLeapSeconds reports it after it expires),
+// and it could be replaced by SetLeapSeconds at runtime when a newer one is published.
+//
+//go:embed leap-seconds.list
+var embeddedLeapSeconds []byte
+
+var (
+	// ErrInvalidLeapSeconds is returned when a leap second table is malformed or its hash mismatches.
+	ErrInvalidLeapSeconds = errors.New("tsc: invalid leap second table")
+	// ErrLeapSecondsExpired is returned by CheckLeapSeconds when the leap second table has expired.
+	ErrLeapSecondsExpired = errors.New("tsc: leap second table has expired")
+	// ErrLeapSecondsMismatch is returned by CheckLeapSeconds when the leap second table
+	// disagrees with the kernel's TAI offset.
+	ErrLeapSecondsMismatch = errors.New("tsc: leap second table mismatches kernel TAI offset")
+)
+
+// leap is an entry of leap second table.
+type leap struct {
+	at     int64 // Unix seconds when offset takes effect.
+	offset int64 // TAI - UTC in seconds.
+}
+
+// LeapSecondTable is a leap second table in IERS leap-seconds.list format, it's immutable.
+type LeapSecondTable struct {
+	updated time.Time
+	expires time.Time
+	leaps   []leap // In ascending order of at.
+}
+
+var leapSeconds atomic.Pointer[LeapSecondTable]
+
+func init() {
+	t, err := ParseLeapSeconds(bytes.NewReader(embeddedLeapSeconds))
+	if err != nil {
+		panic(err)
+	}
+
+	leapSeconds.Store(t)
+}
+
+// LeapSeconds returns the leap second table in use,
+// it's the embedded one unless it's replaced by SetLeapSeconds.
+func LeapSeconds() *LeapSecondTable {
+	return leapSeconds.Load()
+}
+
+// SetLeapSeconds replaces the leap second table in use,
+// e.g. by parsing a newer leap-seconds.list from IERS or tzdata (/usr/share/zoneinfo/leap-seconds.list).
+func SetLeapSeconds(t *LeapSecondTable) {
+	leapSeconds.Store(t)
+}
+
+// ParseLeapSeconds parses a leap second table in IERS leap-seconds.list format.
+// The hash is verified if there is a hash line (#h).
+func ParseLeapSeconds(r io.Reader) (*LeapSecondTable, error) {
+	var (
+		t            LeapSecondTable
+		hashed, hash strings.Builder
+		s            = bufio.NewScanner(r)
+	)
+
+	for line := 0; s.Scan(); line++ {
+		text := s.Text()
+
+		switch {
+		case strings.HasPrefix(text, "#$"), strings.HasPrefix(text, "#@"):
+			ntp, err := strconv.ParseInt(strings.TrimSpace(text[2:]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidLeapSeconds, line+1, err)
+			}
+
+			hashed.WriteString(strings.TrimSpace(text[2:]))
+
+			if text[1] == '$' {
+				t.updated = time.Unix(ntp-ntpEpochOffset, 0).UTC()
+			} else {
+				t.expires = time.Unix(ntp-ntpEpochOffset, 0).UTC()
+			}
+		case strings.HasPrefix(text, "#h"):
+			// Leading zeros of words may be omitted.
+			for _, w := range strings.Fields(text[2:]) {
+				fmt.Fprintf(&hash, "%08s", w)
+			}
+		case strings.HasPrefix(text, "#"), strings.TrimSpace(text) == "":
+		default:
+			data, _, _ := strings.Cut(text, "#")
+
+			fields := strings.Fields(data)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%w: line %d: %q", ErrInvalidLeapSeconds, line+1, text)
+			}
+
+			ntp, err := strconv.ParseInt(fields[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidLeapSeconds, line+1, err)
+			}
+
+			offset, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidLeapSeconds, line+1, err)
+			}
+
+			if n := len(t.leaps); n > 0 && ntp-ntpEpochOffset <= t.leaps[n-1].at {
+				return nil, fmt.Errorf("%w: line %d: not in ascending order", ErrInvalidLeapSeconds, line+1)
+			}
+
+			hashed.WriteString(fields[0] + fields[1])
+			t.leaps = append(t.leaps, leap{at: ntp - ntpEpochOffset, offset: offset})
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(t.leaps) == 0 {
+		return nil, fmt.Errorf("%w: no leap second", ErrInvalidLeapSeconds)
+	}
+
+	if hash.Len() > 0 {
+		sum := sha1.Sum([]byte(hashed.String())) //nolint:gosec // See import.
+		if got := fmt.Sprintf("%x", sum); got != hash.String() {
+			return nil, fmt.Errorf("%w: hash mismatch, exp: %s, got: %s", ErrInvalidLeapSeconds, hash.String(), got)
+		}
+	}
+
+	return &t, nil
+}
+
+// Updated returns when the table was last updated, zero if it's unknown.
+func (t *LeapSecondTable) Updated() time.Time {
+	return t.updated
+}
+
+// Expires returns when the table expires, zero if it's unknown.
+// Conversions of timestamps after it may miss leap seconds announced later.
+func (t *LeapSecondTable) Expires() time.Time {
+	return t.expires
+}
+
+// TAIOffset returns TAI - UTC in seconds at Unix nanoseconds ns.
+// Before 1972 (when UTC had fractional offsets), it's the offset in 1972 (10s).
+func (t *LeapSecondTable) TAIOffset(ns int64) int64 {
+	sec := floorDiv(ns, int64(time.Second))
+
+	// i is the first leap after sec.
+	i := sort.Search(len(t.leaps), func(i int) bool { return t.leaps[i].at > sec })
+	if i == 0 {
+		return t.leaps[0].offset
+	}
+
+	return t.leaps[i-1].offset
+}
+
+// UnixNanoToTAI converts Unix nanoseconds to TAI nanoseconds (since 1970-01-01 00:00:00 TAI),
+// it's the same time scale as CLOCK_TAI.
+//
+// Unix time repeats the last second before an inserted leap second (as Linux does),
+// timestamps in the repeated second are regarded as the first pass (before the leap second).
+func (t *LeapSecondTable) UnixNanoToTAI(ns int64) int64 {
+	return ns + t.TAIOffset(ns)*int64(time.Second)
+}
+
+// TAIToUnixNano converts TAI nanoseconds to Unix nanoseconds.
+//
+// inLeap is true if tai is inside an inserted leap second (23:59:60 UTC),
+// which has no Unix time, ns is the repeated last second before it then (as Linux does).
+func (t *LeapSecondTable) TAIToUnixNano(tai int64) (ns int64, inLeap bool) {
+	sec := floorDiv(tai, int64(time.Second))
+
+	// start is when the leap takes effect in TAI seconds,
+	// an inserted leap second starts one second earlier than the new offset takes effect.
+	start := func(i int) int64 {
+		if i == 0 {
+			return t.leaps[0].at + t.leaps[0].offset
+		}
+
+		return t.leaps[i].at + min(t.leaps[i-1].offset, t.leaps[i].offset)
+	}
+
+	// i is the first leap after sec.
+	i := sort.Search(len(t.leaps), func(i int) bool { return start(i) > sec })
+	if i == 0 {
+		return tai - t.leaps[0].offset*int64(time.Second), false
+	}
+
+	l := t.leaps[i-1]
+
+	return tai - l.offset*int64(time.Second), sec < l.at+l.offset
+}
+
+// UnixNanoToGPS converts Unix nanoseconds to GPS time in nanoseconds (since 1980-01-06 00:00:00 UTC).
+// See UnixNanoToTAI for timestamps in the repeated second.
+func (t *LeapSecondTable) UnixNanoToGPS(ns int64) int64 {
+	return t.UnixNanoToTAI(ns) - (gpsEpoch+gpsTAIOffset)*int64(time.Second)
+}
+
+// GPSToUnixNano converts GPS time in nanoseconds to Unix nanoseconds.
+// See TAIToUnixNano for inLeap.
+func (t *LeapSecondTable) GPSToUnixNano(gps int64) (ns int64, inLeap bool) {
+	return t.TAIToUnixNano(gps + (gpsEpoch+gpsTAIOffset)*int64(time.Second))
+}
+
+// TAINanoLeap returns UnixNano converted to TAI by the leap second table in use,
+// unlike TAINano, it doesn't depend on the kernel's TAI offset (which is 0 if no NTP daemon sets it).
+func TAINanoLeap() int64 {
+	return LeapSeconds().UnixNanoToTAI(UnixNano())
+}
+
+// GPSNano returns UnixNano converted to GPS time by the leap second table in use.
+func GPSNano() int64 {
+	return LeapSeconds().UnixNanoToGPS(UnixNano())
+}
+
+// CheckLeapSeconds checks the leap second table in use against the kernel's TAI offset (by adjtimex, Linux only).
+//
+// Returns ErrLeapSecondsMismatch if the kernel's TAI offset is set (non-zero) but different from the table,
+// or ErrLeapSecondsExpired if the table has expired.
+// The kernel isn't always right, e.g. it may be set by a stale NTP leap file,
+// so it's up to caller to decide which one to trust.
+func CheckLeapSeconds() error {
+	t := LeapSeconds()
+	now := UnixNano()
+
+	if kernel, ok := kernelTAIOffset(); ok && kernel != 0 {
+		if table := t.TAIOffset(now); kernel != table {
+			return fmt.Errorf("%w: table: %ds, kernel: %ds", ErrLeapSecondsMismatch, table, kernel)
+		}
+	}
+
+	if !t.expires.IsZero() && now > t.expires.UnixNano() {
+		return fmt.Errorf("%w: expired at %s", ErrLeapSecondsExpired, t.expires.Format(time.DateOnly))
+	}
+
+	return nil
+}
+
+// floorDiv returns a / b rounded toward negative infinity.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
@@ -0,0 +1,114 @@
+package tsc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLeapSeconds(t *testing.T) {
+	t.Parallel()
+
+	lt, err := ParseLeapSeconds(bytes.NewReader(embeddedLeapSeconds))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lt.leaps) != 28 {
+		t.Fatalf("leap seconds mismatch: exp: 28, got: %d", len(lt.leaps))
+	}
+
+	if lt.Expires().Before(lt.Updated()) {
+		t.Fatalf("expires before updated: %s < %s", lt.Expires(), lt.Updated())
+	}
+
+	// Tampers the last offset, the hash must mismatch.
+	tampered := bytes.Replace(embeddedLeapSeconds, []byte("3692217600      37"), []byte("3692217600      38"), 1)
+	if bytes.Equal(tampered, embeddedLeapSeconds) {
+		t.Fatal("failed to tamper leap second table")
+	}
+
+	if _, err = ParseLeapSeconds(bytes.NewReader(tampered)); !errors.Is(err, ErrInvalidLeapSeconds) {
+		t.Fatalf("error mismatch: exp: %v, got: %v", ErrInvalidLeapSeconds, err)
+	}
+}
+
+func TestLeapSecondConversions(t *testing.T) {
+	t.Parallel()
+
+	lt := LeapSeconds()
+	sec := int64(time.Second)
+
+	// Leap second inserted at the end of 2016-12-31.
+	leap := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+
+	cases := []struct {
+		ns     int64
+		offset int64
+	}{
+		{time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), 10},
+		{time.Date(1980, 1, 6, 0, 0, 0, 0, time.UTC).UnixNano(), 19},
+		{leap - sec/2, 36},
+		{leap, 37},
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano(), 37},
+	}
+
+	for _, c := range cases {
+		if got := lt.TAIOffset(c.ns); got != c.offset {
+			t.Fatalf("offset mismatch at %d: exp: %d, got: %d", c.ns, c.offset, got)
+		}
+
+		tai := lt.UnixNanoToTAI(c.ns)
+		if tai != c.ns+c.offset*sec {
+			t.Fatalf("tai mismatch at %d: exp: %d, got: %d", c.ns, c.ns+c.offset*sec, tai)
+		}
+
+		ns, inLeap := lt.TAIToUnixNano(tai)
+		if ns != c.ns || inLeap {
+			t.Fatalf("round trip mismatch at %d: got: %d, in leap: %t", c.ns, ns, inLeap)
+		}
+
+		gps := lt.UnixNanoToGPS(c.ns)
+		if gps != tai-(gpsEpoch+gpsTAIOffset)*sec {
+			t.Fatalf("gps mismatch at %d: got: %d", c.ns, gps)
+		}
+
+		if ns, _ = lt.GPSToUnixNano(gps); ns != c.ns {
+			t.Fatalf("gps round trip mismatch at %d: got: %d", c.ns, ns)
+		}
+	}
+
+	// GPS - UTC is 18s since 2017.
+	if got := leap + 18*sec - lt.UnixNanoToGPS(leap); got != gpsEpoch*sec {
+		t.Fatalf("gps epoch mismatch: exp: %d, got: %d", gpsEpoch*sec, got)
+	}
+
+	// Inside 2016-12-31 23:59:60, it maps to the repeated 23:59:59.
+	ns, inLeap := lt.TAIToUnixNano(leap + 36*sec + sec/2)
+	if !inLeap || ns != leap-sec/2 {
+		t.Fatalf("leap second mismatch: exp: %d, in leap, got: %d, in leap: %t", leap-sec/2, ns, inLeap)
+	}
+}
+
+func TestCheckLeapSeconds(t *testing.T) {
+	t.Parallel()
+
+	// Expiry depends on the date when tests run, it's reported instead of failing.
+	if exp := LeapSeconds().Expires(); time.Now().After(exp) {
+		t.Skipf("embedded leap second table has expired at %s, update it by `just update-leap-seconds`",
+			exp.Format(time.DateOnly))
+	}
+
+	err := CheckLeapSeconds()
+	if errors.Is(err, ErrLeapSecondsMismatch) {
+		// The kernel may be set by a stale NTP leap file, it's not the table's fault.
+		t.Logf("table mismatches kernel: %v", err)
+
+		return
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package tsc
+
+import (
+	"math"
+	"sync"
+)
+
+// Default configs of Online.
+const (
+	defaultForgetting       = 0.995
+	defaultOnlineMinSamples = 16
+	// onlineScale scales counter deltas to about seconds for keeping the RLS state well-conditioned.
+	onlineScale = 1e9
+)
+
+// OnlineOptions are options of Online.
+// Zero value means default for each field.
+type OnlineOptions struct {
+	// Forgetting is the forgetting factor in (0, 1], weight of a sample decays by it for each newer sample.
+	// 1 means never forgetting, smaller value tracks frequency changes faster but noisier.
+	// 0.995 by default (about 200 samples memory).
+	Forgetting float64
+	// MinSamples is the number of samples which must be observed before publishing.
+	// 16 by default, at least 2.
+	MinSamples int
+}
+
+// Online is an incremental calibrator,
+// it folds one closest-pair sample at a time into a recursive least squares (RLS) state with a forgetting factor,
+// and publishes the updated conversion after each observation once enough samples have been observed.
+//
+// Unlike Calibrate, it doesn't sleep, so it could be driven by any existing periodic loop (e.g. a metrics tick).
+// The interval between observations doesn't need to be regular.
+type Online struct {
+	opts OnlineOptions
+
+	mu     sync.Mutex
+	warmup []Sample
+	origin Sample        // The latest folded sample, state is centered at it.
+	a, b   float64       // reference - origin.Reference = a + b * (counter - origin.Counter) / onlineScale.
+	p      [2][2]float64 // Inverse of the (weighted) information matrix.
+	ms     float64       // Exponentially weighted mean square of prior residuals.
+	est    Estimate
+
+	// Hooks for testing.
+	sample  func() Sample
+	check   func(offset int64, coeff float64) error
+	publish func(offset int64, coeff float64)
+	bound   func(at int64, coeff, base, rate float64)
+}
+
+// NewOnline creates an Online calibrator with options.
+func NewOnline(opts OnlineOptions) *Online {
+	if opts.Forgetting <= 0 || opts.Forgetting > 1 {
+		opts.Forgetting = defaultForgetting
+	}
+
+	if opts.MinSamples <= 0 {
+		opts.MinSamples = defaultOnlineMinSamples
+	}
+
+	opts.MinSamples = max(opts.MinSamples, 2)
+
+	sp := new(sampler)
+
+	return &Online{
+		opts:    opts,
+		sample:  sp.sample,
+		check:   checkCalibration,
+		publish: publishChecked,
+		bound:   setErrorBound,
+	}
+}
+
+// Observe takes a closest-pair sample, folds it into the state, and publishes the updated conversion.
+//
+// Returns ErrUnsupported if the counter is unsupported,
+// ErrTooFewSamples before MinSamples samples have been observed,
+// or ErrImplausibleCalibration if the estimate fails sanity checks (nothing is published then).
+func (o *Online) Observe() (Estimate, error) {
+	if !Supported() {
+		return Estimate{}, ErrUnsupported
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.fold(o.sample()) {
+		return Estimate{}, ErrTooFewSamples
+	}
+
+	if err := o.check(o.est.Offset, o.est.Coeff); err != nil {
+		return Estimate{}, err
+	}
+
+	o.publish(o.est.Offset, o.est.Coeff)
+	o.bound(o.origin.Reference, o.est.Coeff,
+		errorSigmas*o.est.Error+float64(o.origin.Uncertainty)*o.est.Coeff/2, defaultDriftRate)
+
+	return o.est, nil
+}
+
+// Estimate returns the latest estimate,
+// or ErrTooFewSamples before MinSamples samples have been observed.
+func (o *Online) Estimate() (Estimate, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.est.Coeff == 0 {
+		return Estimate{}, ErrTooFewSamples
+	}
+
+	return o.est, nil
+}
+
+// fold folds s into the state, returns true if the estimate is ready.
+func (o *Online) fold(s Sample) bool {
+	if o.est.Coeff == 0 {
+		o.warmup = append(o.warmup, s)
+		if len(o.warmup) < o.opts.MinSamples {
+			return false
+		}
+
+		o.init()
+
+		return true
+	}
+
+	lambda := o.opts.Forgetting
+	w := sampleWeight(s)
+	x := float64(s.Counter-o.origin.Counter) / onlineScale
+	y := float64(s.Reference - o.origin.Reference)
+
+	// Moves origin to s (y is still relative to the old one), after that, the regressor of s is [1, 0].
+	o.shift(x)
+	e := y - o.a
+
+	// Weighted RLS update with regressor [1, 0]:
+	// k = P*phi / (lambda/w + phi'*P*phi), P = (P - k*phi'*P) / lambda.
+	d := lambda/w + o.p[0][0]
+	k0, k1 := o.p[0][0]/d, o.p[1][0]/d
+
+	o.a += k0 * e
+	o.b += k1 * e
+	o.p = [2][2]float64{
+		{(o.p[0][0] - k0*o.p[0][0]) / lambda, (o.p[0][1] - k0*o.p[0][1]) / lambda},
+		{(o.p[1][0] - k1*o.p[0][0]) / lambda, (o.p[1][1] - k1*o.p[0][1]) / lambda},
+	}
+
+	o.ms = lambda*o.ms + (1-lambda)*e*e
+	// Intercept is relative to the old origin, rebase it to s.
+	o.a -= y
+	o.origin = s
+	o.setEstimate()
+
+	return true
+}
+
+// init initializes the state by a batch fit of the warmup samples.
+func (o *Online) init() {
+	ss := o.warmup
+	o.warmup = nil
+
+	xs, ys := centerSamples(ss)
+	ws := sampleWeights(ss)
+
+	for i := range xs {
+		xs[i] /= onlineScale
+	}
+
+	a, b := weightedLinearRegression(xs, ys, ws)
+
+	// P = (X'WX)^-1, X = [1, x].
+	var s0, s1, s2 float64
+	for i, x := range xs {
+		s0 += ws[i]
+		s1 += ws[i] * x
+		s2 += ws[i] * x * x
+	}
+
+	det := s0*s2 - s1*s1
+	o.p = [2][2]float64{{s2 / det, -s1 / det}, {-s1 / det, s0 / det}}
+	o.a, o.b = a, b
+	o.origin = ss[0]
+
+	// Moves origin to the latest sample as fold does.
+	last := ss[len(ss)-1]
+	o.shift(float64(last.Counter-o.origin.Counter) / onlineScale)
+	o.a -= float64(last.Reference - o.origin.Reference)
+	o.origin = last
+
+	coeff := b / onlineScale
+	r := residualRMS(ss, coeff, uncenterOffset(ss[0], a, coeff))
+	o.ms = r * r
+	o.setEstimate()
+}
+
+// shift moves the state along x by transforming it with [1, x; 0, 1].
+func (o *Online) shift(x float64) {
+	o.a += o.b * x
+	o.p = [2][2]float64{
+		{o.p[0][0] + x*(o.p[0][1]+o.p[1][0]) + x*x*o.p[1][1], o.p[0][1] + x*o.p[1][1]},
+		{o.p[1][0] + x*o.p[1][1], o.p[1][1]},
+	}
+}
+
+func (o *Online) setEstimate() {
+	coeff := o.b / onlineScale
+	o.est = Estimate{
+		Coeff:  coeff,
+		Offset: uncenterOffset(o.origin, o.a, coeff),
+		Error:  math.Sqrt(o.ms),
+	}
+}
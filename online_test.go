@@ -0,0 +1,162 @@
+package tsc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// newOnlineForSamples creates an Online which observes ss in order.
+func newOnlineForSamples(opts OnlineOptions, ss []Sample, published *Estimate) *Online {
+	o := NewOnline(opts)
+
+	i := 0
+	o.sample = func() Sample {
+		s := ss[i]
+		i++
+
+		return s
+	}
+	// Samples are not from this machine.
+	o.check = func(int64, float64) error { return nil }
+	o.publish = func(offset int64, coeff float64) {
+		*published = Estimate{Coeff: coeff, Offset: offset}
+	}
+	o.bound = func(int64, float64, float64, float64) {}
+
+	return o
+}
+
+func TestOnline(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	ss := loadSamples(t)
+
+	var published Estimate
+
+	o := newOnlineForSamples(OnlineOptions{Forgetting: 1, MinSamples: 8}, ss, &published)
+
+	for i := range ss {
+		e, err := o.Observe()
+		if i < 7 {
+			if !errors.Is(err, ErrTooFewSamples) || published.Coeff != 0 {
+				t.Fatalf("shouldn't publish before enough samples, got: %v", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if e.Coeff != published.Coeff || e.Offset != published.Offset {
+			t.Fatal("estimate should be published")
+		}
+	}
+
+	// Without forgetting, RLS is the same as the batch weighted least squares.
+	got, err := o.Estimate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp, err := OLS{}.Estimate(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := maxPredictionDelta(ss, exp, got); d > 10 {
+		t.Fatalf("online estimate should be the same as OLS, max delta: %dns, exp: %+v, got: %+v", d, exp, got)
+	}
+}
+
+func TestOnlineForgetting(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	offset := int64(1_700_000_000_000_000_000)
+	ss := makeSamples(512, 0.5, offset, 20)
+
+	// Frequency changes by 1ppm at the middle, the line is continuous.
+	mid := ss[len(ss)/2]
+	for i := len(ss) / 2; i < len(ss); i++ {
+		ss[i].Reference += int64(float64(ss[i].Counter-mid.Counter) * 0.5e-6)
+	}
+
+	for i := range ss {
+		ss[i].Uncertainty = 40
+	}
+
+	var published Estimate
+
+	o := newOnlineForSamples(OnlineOptions{Forgetting: 0.95}, ss, &published)
+
+	var at int64
+
+	var base float64
+
+	o.bound = func(a int64, _, b, _ float64) { at, base = a, b }
+
+	for range ss {
+		_, _ = o.Observe()
+	}
+
+	last := ss[len(ss)-1]
+	if r := residual(last, published.Coeff, published.Offset); r > 100 || r < -100 {
+		t.Fatalf("should track the latest samples, residual: %dns", r)
+	}
+
+	// Error bound is updated by each publish, with the error of the estimate.
+	if r := residual(last, published.Coeff, published.Offset); at != last.Reference || math.Abs(float64(r)) > base || base > 200 {
+		t.Fatalf("error bound should be updated by the latest sample, at: %d, base: %.0fns, residual: %dns", at, base, r)
+	}
+
+	if f := published.Coeff/0.5 - 1; math.Abs(f-1e-6) > 2e-8 {
+		t.Fatalf("should track the new frequency, got error: %.3e", f)
+	}
+}
+
+func TestOnlineUnknownUncertainty(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	ss := makeSamples(64, 0.5, 1_700_000_000_000_000_000, 20)
+
+	// Some of the uncertainties are unknown, they are biased, so the fit depends on how they are weighted.
+	for i := range ss {
+		if i%5 != 0 {
+			ss[i].Uncertainty = 40
+		} else {
+			ss[i].Reference += 500
+		}
+	}
+
+	var published Estimate
+
+	o := newOnlineForSamples(OnlineOptions{Forgetting: 1, MinSamples: 8}, ss, &published)
+
+	for range ss {
+		_, _ = o.Observe()
+	}
+
+	// Batch & online estimators weight samples in the same way.
+	exp, err := OLS{}.Estimate(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := maxPredictionDelta(ss, exp, published); d > 10 {
+		t.Fatalf("online estimate should be the same as OLS, max delta: %dns, exp: %+v, got: %+v", d, exp, published)
+	}
+}
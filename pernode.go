@@ -0,0 +1,202 @@
+package tsc
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxNodes is the max number of NUMA nodes supported by per-node offset.
+const maxNodes = 64
+
+// nodeSamples is the number of closest pairs on each node, the median offset is used.
+const nodeSamples = 16
+
+// ErrPerNodeUnsupported is returned by EnablePerNodeOffset when per-node offset can't work on this platform.
+var ErrPerNodeUnsupported = errors.New("tsc: per-node offset is unsupported on this platform")
+
+// perNodeOffset is 1 if per-node offset is enabled.
+var perNodeOffset int64 = 0
+
+// nodeParams is the statically allocated block for NodeOffsetCoeff, see params for details.
+var nodeParams [(maxNodes + 1) * CacheLineSize]byte
+
+var (
+	// NodeOffsetCoeff is offset & coefficient pair for each NUMA node.
+	// Node N is in [N*CacheLineSize, N*CacheLineSize+16) bytes, with the same layout as OffsetCoeff.
+	// Each node occupies a cache line for avoiding false sharing among sockets.
+	NodeOffsetCoeff     = nodeParams[paramsOffset : paramsOffset+maxNodes*CacheLineSize]
+	NodeOffsetCoeffAddr = &NodeOffsetCoeff[0]
+)
+
+// EnablePerNodeOffset calibrates offset for each NUMA node separately,
+// and makes UnixNano apply the offset of the node where the counter is read.
+//
+// On some multi-socket servers, counters of different packages carry a small constant offset
+// even with invariant TSC, after enabling it timestamps taken on different sockets are comparable
+// (for both UnixNano & UnixNanoCPU).
+// Full calibrations (Calibrate & CalibrateWithOptions) sample the per-node offsets again after that,
+// other publishers (Online, Discipline & DriftModel) move them with the global conversion.
+//
+// Only works on Linux amd64 with RDTSCP (TSC_AUX tells the node),
+// returns ErrPerNodeUnsupported otherwise.
+//
+// Not threads safe.
+func EnablePerNodeOffset() error {
+	if !Supported() || !hasPerNodeFastPath() {
+		return ErrPerNodeUnsupported
+	}
+
+	nodes, err := numaNodes()
+	if err != nil {
+		return fmt.Errorf("tsc: failed to get NUMA nodes: %w", err)
+	}
+
+	for node := range nodes {
+		if node >= maxNodes {
+			return fmt.Errorf("tsc: too many NUMA nodes, node %d is out of range [0, %d)", node, maxNodes)
+		}
+	}
+
+	perNodeOffset = 1
+
+	reset()
+
+	return nil
+}
+
+// DisablePerNodeOffset goes back to the global offset.
+//
+// Not threads safe.
+func DisablePerNodeOffset() {
+	if perNodeOffset == 0 {
+		return
+	}
+
+	perNodeOffset = 0
+
+	reset()
+}
+
+// IsPerNodeOffset returns per-node offset enabled or not.
+//
+// Not threads safe.
+func IsPerNodeOffset() bool {
+	return perNodeOffset == 1
+}
+
+// nodeOffsets are offsets of nodes measured by measurePerNode.
+type nodeOffsets struct {
+	offset [maxNodes]int64
+	ok     [maxNodes]bool // The node has been measured.
+}
+
+// measurePerNode measures offset of each node with the global coeff,
+// by threads pinned to each node, pairs are taken by sp.
+//
+// Nothing is stored, so UnixNano on nodes which haven't been measured doesn't change meanwhile,
+// see storePerNode.
+func measurePerNode(sp *sampler, coeff float64) *nodeOffsets {
+	ns := new(nodeOffsets)
+
+	nodes, err := numaNodes()
+	if err != nil {
+		return ns
+	}
+
+	for node, cpus := range nodes {
+		if node >= maxNodes {
+			continue
+		}
+
+		// One node at a time, calibrating on other nodes concurrently brings noise.
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+
+			ns.offset[node], ns.ok[node] = nodeOffset(sp, cpus, coeff)
+		}()
+
+		<-done
+	}
+
+	return ns
+}
+
+// storePerNode stores offsets measured by measurePerNode with the global coeff, it's called with publishMu held.
+//
+// Nodes which haven't been measured keep their corrections: they are moved with the global conversion
+// (which is changed by step at counter c) as slewPerNode does, or take the global offset if they have none.
+func storePerNode(ns *nodeOffsets, c, offset int64, coeff float64, step int64) {
+	for i := range maxNodes {
+		addr := &NodeOffsetCoeff[i*CacheLineSize]
+
+		switch prevOffset, prevCoeff := LoadOffsetCoeff(addr); {
+		case ns.ok[i]:
+			storeOffsetCoeff(addr, ns.offset[i], coeff)
+		case prevCoeff == 0:
+			storeOffsetCoeff(addr, offset, coeff)
+		default:
+			storeOffsetCoeff(addr, slewOffset(c, prevOffset, prevCoeff, coeff, step), coeff)
+		}
+	}
+}
+
+// nodeOffset gets offset on cpus with coeff by a pinned thread.
+func nodeOffset(sp *sampler, cpus []int, coeff float64) (int64, bool) {
+	// Don't unlock, the thread will be terminated with the goroutine,
+	// so the changed affinity won't leak to other goroutines.
+	runtime.LockOSThread()
+
+	if setAffinity(cpus) != nil {
+		return 0, false
+	}
+
+	offs := make([]int64, nodeSamples)
+
+	for i := range offs {
+		_, tsc, ref := sp.closest()
+		offs[i] = ref - int64(float64(tsc)*coeff)
+	}
+
+	slices.Sort(offs)
+
+	return offs[len(offs)/2], true
+}
+
+// parseCPUList parses Linux cpulist format (e.g. "0-3,8,10-11").
+func parseCPUList(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	var cpus []int
+
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, "-")
+
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("tsc: invalid cpulist %q: %w", s, err)
+		}
+
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(hi)
+			if err != nil {
+				return nil, fmt.Errorf("tsc: invalid cpulist %q: %w", s, err)
+			}
+		}
+
+		for c := start; c <= end; c++ {
+			cpus = append(cpus, c)
+		}
+	}
+
+	return cpus, nil
+}
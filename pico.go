@@ -0,0 +1,102 @@
+package tsc
+
+import (
+	"math"
+	"sync"
+)
+
+// picosPerSecond is the number of picoseconds in a second.
+const picosPerSecond = 1e12
+
+// UnixPico returns the current Unix time as seconds and picoseconds (in [0, 1e12)),
+// computed by the same conversion as UnixNano but without truncating the sub-nanosecond part,
+// e.g. a 4GHz counter resolves 250ps.
+//
+// The product of counter & coeff is carried exactly (by FMA), so the result may differ from UnixNano
+// (which rounds the product first) by the rounding error, it's less than 1ns even for large counter values.
+// Per-node offsets (see EnablePerNodeOffset) are not applied.
+//
+// If the counter is unsupported, the picoseconds are whole nanoseconds by the system clock.
+func UnixPico() (sec, pico int64) {
+	if !Supported() {
+		ns := sysClock()
+		sec = floorDiv(ns, 1e9)
+
+		return sec, (ns - sec*1e9) * 1000
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	return unixPico(int64(NowTicks()), offset, coeff)
+}
+
+// unixPico converts counter value c to Unix seconds & picoseconds by offset & coeff.
+func unixPico(c, offset int64, coeff float64) (sec, pico int64) {
+	x := float64(c)
+	p := x * coeff
+	// e is the rounding error of p, p + e is the exact product (x is exact for counter values < 2^53).
+	e := math.FMA(x, coeff, -p)
+
+	whole := math.Floor(p)
+	ns := offset + int64(whole)
+	// Fraction of nanosecond, it may be slightly out of [0, 1) because of e.
+	frac := (p - whole) + e
+
+	sec = floorDiv(ns, 1e9)
+	pico = (ns-sec*1e9)*1000 + int64(math.Round(frac*1000))
+
+	switch {
+	case pico < 0:
+		sec--
+		pico += picosPerSecond
+	case pico >= picosPerSecond:
+		sec++
+		pico -= picosPerSecond
+	}
+
+	return sec, pico
+}
+
+// ElapsedFloat converts ticks (as a difference) to fractional nanoseconds by the current coeff,
+// it's Ticks.Duration without truncating the sub-nanosecond part.
+func ElapsedFloat(t Ticks) float64 {
+	return float64(t) * currentCoeff()
+}
+
+// Resolution returns the effective granularity of the counter in (fractional) nanoseconds,
+// which is the smallest step the counter advances by times the current coeff,
+// e.g. 0.25 for a 4GHz TSC, or about 41.67 for a 24MHz ARM generic timer.
+//
+// It's the smallest difference timestamps could distinguish, not the cost of reading the counter.
+// The step is measured at the first call.
+func Resolution() float64 {
+	return float64(counterStep()) * currentCoeff()
+}
+
+// counterStep returns the greatest common divisor of differences between consecutive counter values,
+// a counter which advances by more than 1 per update (e.g. some virtualized counters) has step > 1.
+var counterStep = sync.OnceValue(func() int64 {
+	const n = 1024
+
+	var step int64
+
+	last := int64(NowTicks())
+	for range n {
+		c := int64(NowTicks())
+		if d := c - last; d > 0 {
+			step = gcd(step, d)
+		}
+
+		last = c
+	}
+
+	return max(step, 1)
+})
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
@@ -0,0 +1,84 @@
+package tsc
+
+import (
+	"math/big"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestUnixPicoConversion(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for range 10000 {
+		c := rng.Int64N(1 << 52)
+		coeff := 0.1 + rng.Float64()*100
+		offset := 1_700_000_000_000_000_000 - rng.Int64N(1<<62)
+
+		sec, pico := unixPico(c, offset, coeff)
+		if pico < 0 || pico >= picosPerSecond {
+			t.Fatalf("pico out of range: %d", pico)
+		}
+
+		// Exact: c * coeff + offset in picoseconds.
+		exp := new(big.Float).SetPrec(256).SetInt64(c)
+		exp.Mul(exp, new(big.Float).SetPrec(256).SetFloat64(coeff))
+		exp.Add(exp, new(big.Float).SetPrec(256).SetInt64(offset))
+		exp.Mul(exp, big.NewFloat(1000))
+
+		got := new(big.Float).SetPrec(256).SetInt64(sec)
+		got.Mul(got, big.NewFloat(picosPerSecond))
+		got.Add(got, new(big.Float).SetPrec(256).SetInt64(pico))
+
+		if d, _ := got.Sub(got, exp).Float64(); d < -1 || d > 1 {
+			t.Fatalf("pico mismatch: c: %d, coeff: %g, offset: %d, diff: %gps", c, coeff, offset, d)
+		}
+	}
+}
+
+func TestUnixPico(t *testing.T) {
+	t.Parallel()
+
+	for range 1000 {
+		ns0 := UnixNano()
+		sec, pico := UnixPico()
+		ns1 := UnixNano()
+
+		// Out-of-order reading.
+		const tolerance = 1000
+
+		ns := sec*1e9 + pico/1000
+		if ns < ns0-tolerance || ns > ns1+tolerance {
+			t.Fatalf("pico mismatch: %d not in [%d, %d]", ns, ns0, ns1)
+		}
+	}
+}
+
+func TestElapsedFloat(t *testing.T) {
+	t.Parallel()
+
+	ticks := Ticks(1_000_003)
+
+	got := ElapsedFloat(ticks)
+	if d := got - float64(ticks.Duration()); d < 0 || d >= 1 {
+		t.Fatalf("elapsed mismatch: duration: %d, got: %f", ticks.Duration(), got)
+	}
+}
+
+func TestResolution(t *testing.T) {
+	t.Parallel()
+
+	r := Resolution()
+	if r < currentCoeff()*(1-1e-9) {
+		t.Fatalf("resolution should be at least one tick (%gns), got: %gns", currentCoeff(), r)
+	}
+
+	t.Logf("resolution: %gns", r)
+}
+
+func BenchmarkUnixPico(b *testing.B) {
+	for range b.N {
+		_, _ = UnixPico()
+	}
+}
@@ -0,0 +1,75 @@
+package tsc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// Bounds of plausible calibration results.
+const (
+	// minFrequency & maxFrequency are the plausible counter frequencies in Hz,
+	// ARM Generic Timers may run at 1MHz, and no TSC runs faster than 10GHz.
+	minFrequency = 1e6
+	maxFrequency = 1e10
+	// maxNominalDeviation is the max relative deviation from the frequency reported by hardware (CPUID/CNTFRQ).
+	maxNominalDeviation = 0.02
+	// maxCoeffChange is the max relative change of coeff versus the last checked one (see checkedCoeff),
+	// invariant counters don't change frequency, it's far beyond crystal wander.
+	maxCoeffChange = 1e-3
+)
+
+// ErrImplausibleCalibration is returned when a calibration result fails sanity checks,
+// e.g. the counter froze (in some VMs) and the estimated coeff is NaN.
+var ErrImplausibleCalibration = errors.New("tsc: implausible calibration result")
+
+// checkedCoeff is the coeff (in float64 bits) of the last calibration which passed checkCalibration and was published,
+// 0 if there is none.
+//
+// The change of coeff is checked against it instead of the published one,
+// so a bad coeff published without checking (e.g. by CalibrateWithCoeff) doesn't make later calibrations rejected.
+var checkedCoeff atomic.Uint64
+
+// acceptCalibration records coeff which passed checkCalibration and has been published.
+func acceptCalibration(coeff float64) {
+	checkedCoeff.Store(math.Float64bits(coeff))
+}
+
+// checkCalibration checks the result before publishing:
+// checkPlausible, and a bounded change versus the last checked coeff.
+func checkCalibration(offset int64, coeff float64) error {
+	if err := checkPlausible(offset, coeff); err != nil {
+		return err
+	}
+
+	if prev := math.Float64frombits(checkedCoeff.Load()); prev > 0 && math.Abs(coeff/prev-1) > maxCoeffChange {
+		return fmt.Errorf("%w: coeff changes too much from %.16f to %.16f", ErrImplausibleCalibration, prev, coeff)
+	}
+
+	return nil
+}
+
+// checkPlausible checks finite values, and a plausible frequency which is consistent with the one reported by hardware.
+func checkPlausible(offset int64, coeff float64) error {
+	if math.IsNaN(coeff) || math.IsInf(coeff, 0) || coeff <= 0 {
+		return fmt.Errorf("%w: coeff is %v", ErrImplausibleCalibration, coeff)
+	}
+
+	freq := 1e9 / coeff
+	if freq < minFrequency || freq > maxFrequency {
+		return fmt.Errorf("%w: frequency %.0f Hz is out of [%.0f, %.0f]", ErrImplausibleCalibration, freq, minFrequency, maxFrequency)
+	}
+
+	// Offset is got from coeff & a finite reference, it overflows only if coeff is absurd.
+	if offset == math.MinInt64 || offset == math.MaxInt64 {
+		return fmt.Errorf("%w: offset overflows", ErrImplausibleCalibration)
+	}
+
+	if nominal := nominalFrequency(); nominal > 0 && math.Abs(freq/nominal-1) > maxNominalDeviation {
+		return fmt.Errorf("%w: frequency %.0f Hz is inconsistent with %.0f Hz reported by hardware",
+			ErrImplausibleCalibration, freq, nominal)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package tsc
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCheckCalibration(t *testing.T) {
+	t.Parallel()
+
+	// Counter froze, all samples have the same counter value.
+	frozen := makeSamples(16, 0.5, 1_700_000_000_000_000_000, 0)
+	for i := range frozen {
+		frozen[i].Counter = frozen[0].Counter
+	}
+
+	e, err := OLS{}.Estimate(frozen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		offset int64
+		coeff  float64
+	}{
+		{"frozen", e.Offset, e.Coeff},
+		{"nan", 0, math.NaN()},
+		{"inf", 0, math.Inf(1)},
+		{"zero", 0, 0},
+		{"negative", 0, -0.5},
+		{"too slow", 0, 1e4},
+		{"too fast", 0, 1e-2},
+	} {
+		if err := checkCalibration(tc.offset, tc.coeff); !errors.Is(err, ErrImplausibleCalibration) {
+			t.Fatalf("%s should be implausible, got: %v", tc.name, err)
+		}
+	}
+
+	if !Supported() {
+		return
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	if err := checkPlausible(offset, coeff); err != nil {
+		t.Fatalf("published parameters should be plausible, got: %v", err)
+	}
+
+	prev := math.Float64frombits(checkedCoeff.Load())
+	if err := checkCalibration(offset, prev*1.01); !errors.Is(err, ErrImplausibleCalibration) {
+		t.Fatalf("too big change should be implausible, got: %v", err)
+	}
+}
+
+//nolint:paralleltest // Calibrations change global parameters.
+func TestCalibrationRecovers(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	opts := CalibrationOptions{Samples: 16, Interval: 4 * time.Millisecond}
+
+	_, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	defer Calibrate()
+
+	// A bad coeff published without checking doesn't make later calibrations rejected.
+	CalibrateWithCoeff(coeff * 1.01)
+
+	if _, err := CalibrateWithOptions(opts); err != nil {
+		t.Fatalf("calibration should recover from unchecked coeff, got: %v", err)
+	}
+
+	// A disturbed calibration passed checks, later ones are rejected unless SkipChangeCheck.
+	acceptCalibration(coeff * 1.01)
+
+	if _, err := CalibrateWithOptions(opts); !errors.Is(err, ErrImplausibleCalibration) {
+		t.Fatalf("too big change should be implausible, got: %v", err)
+	}
+
+	opts.SkipChangeCheck = true
+	if _, err := CalibrateWithOptions(opts); err != nil {
+		t.Fatalf("calibration should skip change check, got: %v", err)
+	}
+
+	opts.SkipChangeCheck = false
+	if _, err := CalibrateWithOptions(opts); err != nil {
+		t.Fatalf("calibration should recover after skipping change check, got: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package tsc
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// generation is the number of times parameters have been published, it's changed under publishMu.
+var generation atomic.Uint64
+
+// fmaConversion indicates UnixNano converts by FMA (one rounding) instead of MUL & ADD,
+// it's set by reset.
+var fmaConversion bool
+
+// Snapshot is the conversion in effect at some moment, it's immutable.
+//
+// It's useful for converting raw counter values (captured on hot paths) later,
+// and for testing conversion differentially.
+type Snapshot struct {
+	coeff      float64
+	offset     int64
+	generation uint64
+	fma        bool
+}
+
+// CurrentSnapshot returns the conversion in effect now.
+//
+// If the counter is unsupported, the conversion is identity (counter values are nanoseconds by the system clock),
+// see Ticks for details.
+func CurrentSnapshot() Snapshot {
+	if !Supported() {
+		return Snapshot{coeff: 1}
+	}
+
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	return Snapshot{
+		coeff:      coeff,
+		offset:     offset,
+		generation: generation.Load(),
+		fma:        fmaConversion,
+	}
+}
+
+// Coeff returns the coeff (nanoseconds per tick).
+func (s Snapshot) Coeff() float64 {
+	return s.coeff
+}
+
+// Offset returns the offset in nanoseconds.
+func (s Snapshot) Offset() int64 {
+	return s.offset
+}
+
+// Generation returns the generation of the conversion,
+// which is changed each time the conversion is published (e.g. by Calibrate).
+// Two snapshots with the same generation are the same conversion.
+func (s Snapshot) Generation() uint64 {
+	return s.generation
+}
+
+// Frequency returns the counter frequency in Hz,
+// or 0 if the counter is unsupported.
+func (s Snapshot) Frequency() float64 {
+	if !Supported() {
+		return 0
+	}
+
+	return 1e9 / s.coeff
+}
+
+// ToUnixNano converts counter value cycles to Unix nanoseconds,
+// it exactly matches UnixNano (which reads the counter & converts it in assembly),
+// except in per-node offset mode (see EnablePerNodeOffset), where each node has its own offset.
+func (s Snapshot) ToUnixNano(cycles int64) int64 {
+	if s.fma {
+		return int64(math.FMA(float64(cycles), s.coeff, float64(s.offset)))
+	}
+
+	return int64(float64(cycles)*s.coeff) + s.offset
+}
+
+// ToCycles converts Unix nanoseconds to counter value, it's the inverse of ToUnixNano
+// (ToUnixNano(ToCycles(ns)) is within one tick of ns).
+func (s Snapshot) ToCycles(ns int64) int64 {
+	return int64(math.Round(float64(ns-s.offset) / s.coeff))
+}
@@ -0,0 +1,81 @@
+package tsc
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	r := rand.New(rand.NewSource(1))
+
+	for _, fma := range []bool{false, true} {
+		s := Snapshot{coeff: 1 / 2.9, offset: 1_700_000_000_000_000_000, fma: fma}
+
+		for range 10000 {
+			cycles := r.Int63n(1 << 50)
+
+			ns := s.ToUnixNano(cycles)
+			if got := s.ToUnixNano(s.ToCycles(ns)); got-ns > 1 || ns-got > 1 {
+				t.Fatalf("round trip mismatch (fma: %t), exp: %d, got: %d", fma, ns, got)
+			}
+		}
+	}
+}
+
+//nolint:paralleltest
+func TestCurrentSnapshot(t *testing.T) {
+	if !Supported() {
+		if s := CurrentSnapshot(); s.ToUnixNano(100) != 100 {
+			t.Fatal("conversion should be identity if unsupported")
+		}
+
+		return
+	}
+
+	s := CurrentSnapshot()
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	if s.Offset() != offset || s.Coeff() != coeff || s.Frequency() != Frequency() {
+		t.Fatalf("snapshot mismatch: %+v", s)
+	}
+
+	CalibrateWithCoeff(coeff)
+
+	if g := CurrentSnapshot().Generation(); g != s.Generation()+1 {
+		t.Fatalf("generation should be changed by publishing, exp: %d, got: %d", s.Generation()+1, g)
+	}
+}
+
+// checkSnapshotBrackets checks UnixNano implementation read is bracketed by snap converting counters read in order:
+// snap().ToUnixNano(before) <= read() <= snap().ToUnixNano(after).
+func checkSnapshotBrackets(t *testing.T, name string, snap func() Snapshot, read func() int64) {
+	t.Helper()
+
+	for range 1000 {
+		s := snap()
+
+		before := GetInOrder()
+		ns := read()
+		after := GetInOrder()
+
+		if snap().Generation() != s.Generation() {
+			continue // Published in the middle.
+		}
+
+		if lo, hi := s.ToUnixNano(before), s.ToUnixNano(after); ns < lo || ns > hi {
+			t.Fatalf("%s isn't bracketed by snapshot: %d not in [%d, %d]", name, ns, lo, hi)
+		}
+	}
+}
+
+func TestSnapshotBracketsUnixNano(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	checkSnapshotBrackets(t, "UnixNano", CurrentSnapshot, UnixNano)
+}
@@ -0,0 +1,4 @@
+package tsc
+
+// sysGetcpu is missing in package syscall on linux/amd64.
+const sysGetcpu = 309
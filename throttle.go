@@ -0,0 +1,125 @@
+package tsc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where cgroup hierarchies are mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// throttleMonitor reads nr_throttled in cpu.stat of the cgroup cpu controller,
+// for detecting samples which may be disturbed by CPU quota throttling.
+//
+// nil means throttling can't be detected (no cgroup or no cpu controller).
+type throttleMonitor struct {
+	f   *os.File
+	buf []byte
+}
+
+// newThrottleMonitor opens cpu.stat of the cgroup which current process belongs to,
+// returns nil if it's unavailable.
+func newThrottleMonitor() *throttleMonitor {
+	d, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return nil
+	}
+
+	for _, p := range cgroupCPUStatPaths(string(d)) {
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+
+		m := &throttleMonitor{f: f, buf: make([]byte, 512)}
+		if m.nrThrottled() >= 0 {
+			return m
+		}
+
+		_ = f.Close()
+	}
+
+	return nil
+}
+
+// nrThrottled returns the number of throttled periods, or -1 if it can't be read.
+func (m *throttleMonitor) nrThrottled() int64 {
+	if m == nil {
+		return -1
+	}
+
+	n, err := m.f.ReadAt(m.buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return -1
+	}
+
+	v, ok := parseNrThrottled(m.buf[:n])
+	if !ok {
+		return -1
+	}
+
+	return v
+}
+
+func (m *throttleMonitor) close() {
+	if m != nil {
+		_ = m.f.Close()
+	}
+}
+
+// cgroupCPUStatPaths returns candidates of cpu.stat by /proc/self/cgroup content,
+// cgroup v1 cpu controller goes first, because cgroup v2 may be mounted without cpu controller in hybrid mode.
+func cgroupCPUStatPaths(procSelfCgroup string) []string {
+	var v1, v2 []string
+
+	for _, line := range strings.Split(procSelfCgroup, "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		controllers, path := parts[1], parts[2]
+
+		if parts[0] == "0" && controllers == "" {
+			v2 = append(v2,
+				filepath.Join(cgroupRoot, path, "cpu.stat"),
+				filepath.Join(cgroupRoot, "unified", path, "cpu.stat"))
+
+			continue
+		}
+
+		for _, c := range strings.Split(controllers, ",") {
+			if c == "cpu" {
+				v1 = append(v1,
+					filepath.Join(cgroupRoot, controllers, path, "cpu.stat"),
+					filepath.Join(cgroupRoot, "cpu", path, "cpu.stat"))
+
+				break
+			}
+		}
+	}
+
+	return append(v1, v2...)
+}
+
+// parseNrThrottled parses nr_throttled in cpu.stat.
+func parseNrThrottled(d []byte) (int64, bool) {
+	for _, line := range bytes.Split(d, []byte("\n")) {
+		v, ok := bytes.CutPrefix(line, []byte("nr_throttled "))
+		if !ok {
+			continue
+		}
+
+		n, err := strconv.ParseInt(string(bytes.TrimSpace(v)), 10, 64)
+
+		return n, err == nil
+	}
+
+	return 0, false
+}
@@ -0,0 +1,158 @@
+package tsc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCgroupCPUStatPaths(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		cgroup string
+		exp    []string
+	}{
+		{"v2", "0::/system.slice/app.service\n", []string{
+			"/sys/fs/cgroup/system.slice/app.service/cpu.stat",
+			"/sys/fs/cgroup/unified/system.slice/app.service/cpu.stat",
+		}},
+		{"hybrid", "4:memory:/app\n2:cpu,cpuacct:/app\n0::/\n", []string{
+			"/sys/fs/cgroup/cpu,cpuacct/app/cpu.stat",
+			"/sys/fs/cgroup/cpu/app/cpu.stat",
+			"/sys/fs/cgroup/cpu.stat",
+			"/sys/fs/cgroup/unified/cpu.stat",
+		}},
+		{"no cpu controller", "3:cpuacct:/\n", nil},
+	} {
+		if got := cgroupCPUStatPaths(tc.cgroup); !slices.Equal(got, tc.exp) {
+			t.Fatalf("%s mismatch, exp: %v, got: %v", tc.name, tc.exp, got)
+		}
+	}
+}
+
+func TestThrottleMonitor(t *testing.T) {
+	t.Parallel()
+
+	p := filepath.Join(t.TempDir(), "cpu.stat")
+
+	err := os.WriteFile(p, []byte("usage_usec 100\nnr_periods 10\nnr_throttled 3\nthrottled_usec 20\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &throttleMonitor{f: f, buf: make([]byte, 512)}
+	defer m.close()
+
+	if n := m.nrThrottled(); n != 3 {
+		t.Fatalf("nr_throttled mismatch, exp: 3, got: %d", n)
+	}
+
+	if err = os.WriteFile(p, []byte("nr_periods 10\nnr_throttled 4\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := m.nrThrottled(); n != 4 {
+		t.Fatalf("should read the latest one, exp: 4, got: %d", n)
+	}
+
+	var nilMonitor *throttleMonitor
+	if n := nilMonitor.nrThrottled(); n != -1 {
+		t.Fatalf("nil monitor should return -1, got: %d", n)
+	}
+
+	if _, ok := parseNrThrottled([]byte("usage_usec 100\n")); ok {
+		t.Fatal("should fail without nr_throttled")
+	}
+}
+
+//nolint:paralleltest // Calibration publishes global parameters.
+func TestCalibrateThrottled(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	if raceDetectorEnabled {
+		t.Skip("race detector affects timing accuracy")
+	}
+
+	defer Calibrate()
+
+	p := filepath.Join(t.TempDir(), "cpu.stat")
+
+	writeStat := func(n int) {
+		if err := os.WriteFile(p, fmt.Appendf(nil, "nr_periods 100\nnr_throttled %d\n", n), 0o600); err != nil {
+			t.Error(err)
+		}
+	}
+
+	writeStat(0)
+
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &throttleMonitor{f: f, buf: make([]byte, 512)}
+	defer m.close()
+
+	const (
+		retries = 16
+		// Tries in [throttleFrom, throttleTo) are throttled,
+		// it's longer than all tries of a sample, so at least one sample is throttled in all tries.
+		throttleFrom = 10
+		throttleTo   = throttleFrom + 2*(sampleRetries+1)
+		// Reference clock read in throttled tries is disturbed,
+		// the fit would be far away from the system clock if any of them is used.
+		disturbance = int64(10 * time.Millisecond)
+	)
+
+	calls, throttled := 0, 0
+	ref := func() int64 {
+		try := calls / retries
+		first := calls%retries == 0
+		calls++
+
+		if try < throttleFrom || try >= throttleTo {
+			return sysClock()
+		}
+
+		// nr_throttled increases between the reads around the try.
+		if first {
+			throttled++
+			writeStat(throttled)
+		}
+
+		return sysClock() + disturbance
+	}
+
+	opts := CalibrationOptions{Samples: 16, Interval: 4 * time.Millisecond, Retries: retries, Reference: ref}
+
+	r, err := calibrateWith(context.Background(), opts, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Throttled == 0 || r.Dropped < r.Throttled {
+		t.Fatalf("throttled samples should be dropped, throttled: %d, dropped: %d", r.Throttled, r.Dropped)
+	}
+
+	if r.Samples+r.Dropped != 2*opts.Samples {
+		t.Fatalf("samples mismatch, used: %d, dropped: %d, exp total: %d", r.Samples, r.Dropped, 2*opts.Samples)
+	}
+
+	// Only clean samples are fitted.
+	if d := UnixNano() - time.Now().UnixNano(); d > 100000 || d < -100000 {
+		t.Fatalf("fit uses disturbed samples, away from system clock: %dns, result: %+v", d, r)
+	}
+}
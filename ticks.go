@@ -0,0 +1,71 @@
+package tsc
+
+import (
+	"time"
+)
+
+// Ticks is a raw counter value (got by RDTSC or GetInOrder), or a difference of two counter values.
+//
+// Elapsed time computed by Ticks is the cycle difference times the current coeff,
+// so a duration spanning a calibration is never corrupted by an offset step,
+// unlike subtracting two UnixNano results.
+//
+// If the counter is unsupported, Ticks are nanoseconds by the system clock.
+type Ticks int64
+
+// NowTicks returns the current counter value,
+// it's read in strict order by GetInOrder unless out-of-order is allowed (see AllowOutOfOrder).
+func NowTicks() Ticks {
+	if !Supported() {
+		return Ticks(sysClock())
+	}
+
+	if IsOutOfOrder() {
+		return Ticks(RDTSC())
+	}
+
+	return Ticks(GetInOrder())
+}
+
+// Sub returns the ticks t-u.
+func (t Ticks) Sub(u Ticks) Ticks {
+	return t - u
+}
+
+// Duration converts ticks (as a difference) to duration by the current coeff.
+func (t Ticks) Duration() time.Duration {
+	return time.Duration(float64(t) * currentCoeff())
+}
+
+// Seconds converts ticks (as a difference) to seconds by the current coeff.
+func (t Ticks) Seconds() float64 {
+	return float64(t) * currentCoeff() / 1e9
+}
+
+// Since returns the time elapsed since t.
+// It's shorthand for NowTicks().Sub(t).Duration().
+func Since(t Ticks) time.Duration {
+	return NowTicks().Sub(t).Duration()
+}
+
+// Frequency returns the calibrated counter frequency in Hz,
+// or 0 if the counter is unsupported.
+func Frequency() float64 {
+	if !Supported() {
+		return 0
+	}
+
+	return 1e9 / currentCoeff()
+}
+
+// currentCoeff returns the published coeff (nanoseconds per tick),
+// it's 1 if the counter is unsupported.
+func currentCoeff() float64 {
+	if !Supported() {
+		return 1
+	}
+
+	_, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	return coeff
+}
@@ -0,0 +1,45 @@
+package tsc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTicks(t *testing.T) {
+	t.Parallel()
+
+	start, sysStart := NowTicks(), time.Now()
+
+	time.Sleep(10 * time.Millisecond)
+
+	elapsed, sysElapsed := Since(start), time.Since(sysStart)
+
+	if d := elapsed - sysElapsed; d > 100*time.Microsecond || d < -100*time.Microsecond {
+		t.Fatalf("elapsed mismatch, exp: %s, got: %s", sysElapsed, elapsed)
+	}
+
+	end := NowTicks()
+	if got := end.Sub(start).Seconds(); got < sysElapsed.Seconds() {
+		t.Fatalf("seconds should be at least %f, got: %f", sysElapsed.Seconds(), got)
+	}
+
+	if !Supported() {
+		if Frequency() != 0 {
+			t.Fatal("frequency should be 0 if unsupported")
+		}
+
+		return
+	}
+
+	if f := Frequency(); f < minFrequency || f > maxFrequency {
+		t.Fatalf("implausible frequency: %.0f", f)
+	}
+}
+
+func BenchmarkSince(b *testing.B) {
+	start := NowTicks()
+
+	for range b.N {
+		_ = Since(start)
+	}
+}
@@ -28,21 +28,43 @@ var (
 // We could regard coeff as the inverse of TSCFrequency(GHz) (actually it just has mathematics property)
 // for avoiding future dividing.
 // MUL gets much better performance than DIV.
+//
+// Parameters live in statically allocated blocks which are addressed by assembly directly,
+// so there is no pointer to load before loading them in the fast path.
+//
+// Go linker aligns data symbols bigger than 32 bytes to 32 bytes (there is no way to ask for more),
+// so a slot at paramsOffset followed by another paramsOffset bytes padding
+// is always in a cache line occupied by itself, which avoids cache pollution.
+// Offsets in blocks are fixed in assembly.
+const paramsOffset = 32
+
+// params is [padding, OffsetCoeff, OffsetCoeffF, padding].
+var params [2 * CacheLineSize]byte
+
 var (
 	// OffsetCoeff is offset & coefficient pair.
 	// Coefficient is in [0,64) bits.
 	// Offset is in [64, 128) bits.
-	// Using cache line size for alignment to avoid cache pollution.
-	OffsetCoeff     = xbytes.MakeAlignedBlock(CacheLineSize, CacheLineSize)
+	OffsetCoeff     = params[paramsOffset : paramsOffset+16]
 	OffsetCoeffAddr = &OffsetCoeff[0]
 )
 
 var (
 	// OffsetCoeffF using float64 as offset.
-	OffsetCoeffF     = xbytes.MakeAlignedBlock(CacheLineSize, CacheLineSize)
+	OffsetCoeffF     = params[paramsOffset+16 : paramsOffset+32]
 	OffsetCoeffFAddr = &OffsetCoeffF[0]
 )
 
+func init() {
+	// 16 bytes alignment is required by 16 bytes atomic store/load (VMOVDQA),
+	// it should never happen unless the linker changed its rules.
+	for _, b := range [][]byte{OffsetCoeff, NodeOffsetCoeff, clockParams[paramsOffset:]} {
+		if xbytes.Alignment(b, paramsOffset) != 0 {
+			panic("tsc: conversion parameters are not aligned")
+		}
+	}
+}
+
 // UnixNano returns time as a Unix time, the number of nanoseconds elapsed
 // since January 1, 1970 UTC.
 //
@@ -62,13 +84,28 @@ var (
 // because all instructions for getting tsc are not serializing,
 // we need to be careful to deal with the order (use barrier).
 //
-// See GetInOrder in tsc_amd64.s for more details.
+// See GetInOrder (and the ordered reads in tsc_amd64.s) for more details.
 var UnixNano = sysClock
 
 func sysClock() int64 {
 	return time.Now().UnixNano()
 }
 
+// UnixNanoCPU returns UnixNano with the CPU & NUMA node where the timestamp was taken.
+//
+// On Linux amd64 both of them come from one RDTSCP (TSC_AUX is node<<12 | cpu),
+// or RDPID where RDTSCP is missing, so the location is consistent with the timestamp.
+// Otherwise, it falls back to getcpu, which may race with thread migration.
+//
+// cpu & node are -1 if they can't be got on this platform.
+var UnixNanoCPU = unixNanoGetcpu
+
+func unixNanoGetcpu() (int64, int, int) {
+	cpu, node := getcpu()
+
+	return UnixNano(), cpu, node
+}
+
 // Supported indicates Invariant TSC supported.
 func Supported() bool {
 	return supported == 1
@@ -133,35 +170,17 @@ func GetCurrentClockSource() string {
 	return strings.TrimRight(string(d), "\n")
 }
 
-// simpleLinearRegression performs simple linear regression on counter vs system time.
+// getClosestTSCSys tries to get the closest counter value nearby the system clock in a loop.
 // Shared by both AMD64 and ARM64 calibration.
-func simpleLinearRegression(tscs, syss []float64) (float64, int64) {
-	tmean, wmean := float64(0), float64(0)
-	for _, i := range tscs {
-		tmean += i
-	}
-
-	for _, i := range syss {
-		wmean += i
-	}
-
-	tmean /= float64(len(tscs))
-	wmean /= float64(len(syss))
-
-	denominator, numerator := float64(0), float64(0)
-	for i := range tscs {
-		numerator += (tscs[i] - tmean) * (syss[i] - wmean)
-		denominator += (tscs[i] - tmean) * (tscs[i] - tmean)
-	}
-
-	coeff := numerator / denominator
-
-	return coeff, int64(wmean - coeff*tmean)
+//
+// Returns the min counter delta around the system clock (the uncertainty of the pair),
+// the counter value and the system clock.
+func getClosestTSCSys(n int) (int64, int64, int64) {
+	return getClosestTSCRef(n, sysClock)
 }
 
-// getClosestTSCSys tries to get the closest counter value nearby the system clock in a loop.
-// Shared by both AMD64 and ARM64 calibration.
-func getClosestTSCSys(n int) (int64, int64) {
+// getClosestTSCRef is getClosestTSCSys with reference clock ref.
+func getClosestTSCRef(n int, ref func() int64) (int64, int64, int64) {
 	// 256 is enough for finding the lowest sys clock cost in most cases.
 	// Although time.Now() is using VDSO to get time, but it's unstable,
 	// sometimes it will take more than 1000ns,
@@ -172,7 +191,7 @@ func getClosestTSCSys(n int) (int64, int64) {
 
 	timeline[0] = RDTSC()
 	for i := 1; i < len(timeline)-1; i += 2 {
-		timeline[i] = time.Now().UnixNano()
+		timeline[i] = ref()
 		timeline[i+1] = RDTSC()
 	}
 
@@ -209,5 +228,5 @@ func getClosestTSCSys(n int) (int64, int64) {
 	tscClock := (timeline[minIndex+1] + timeline[minIndex-1]) >> 1
 	sys := timeline[minIndex]
 
-	return tscClock, sys
+	return minDelta, tscClock, sys
 }
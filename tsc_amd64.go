@@ -1,19 +1,11 @@
 package tsc
 
 import (
-	"time"
+	"runtime"
 
 	"github.com/templexxx/cpu"
 )
 
-// Configs of calibration.
-// See tools/calibrate for details.
-const (
-	samples                 = 128
-	sampleDuration          = 16 * time.Millisecond
-	getClosestTSCSysRetries = 256
-)
-
 func init() {
 	_ = reset()
 }
@@ -23,7 +15,39 @@ func reset() bool {
 		return false
 	}
 
-	Calibrate()
+	fmaConversion = false
+
+	// If the calibration is rejected, the published parameters may be none (zero) on the first one,
+	// the system clock is used instead.
+	if _, err := CalibrateWithOptions(CalibrationOptions{}); err != nil {
+		UnixNano = sysClock
+		UnixNanoCPU = unixNanoGetcpu
+
+		return false
+	}
+
+	// TSC_AUX is only set as node<<12 | cpu by Linux.
+	if runtime.GOOS == "linux" {
+		switch {
+		case x86.hasRDTSCP:
+			UnixNanoCPU = unixNanoCPURDTSCP
+		case x86.hasRDPID:
+			UnixNanoCPU = unixNanoCPURDPID
+		}
+	}
+
+	if IsPerNodeOffset() {
+		// Per-node offset needs RDTSCP, see EnablePerNodeOffset.
+		UnixNanoCPU = unixNanoCPUNode
+
+		if IsOutOfOrder() {
+			UnixNano = unixNanoTSCNode
+		} else {
+			UnixNano = unixNanoTSCNodeFence
+		}
+
+		return true
+	}
 
 	if IsOutOfOrder() {
 		if cpu.X86.HasFMA {
@@ -43,6 +67,9 @@ func reset() bool {
 			tscCost := GetInOrder() - start
 			if fmaCost < tscCost {
 				UnixNano = unixNanoTSCFMA
+				fmaConversion = true
+
+				return true
 			}
 		}
 
@@ -51,11 +78,234 @@ func reset() bool {
 		return true
 	}
 
-	UnixNano = unixNanoTSC16Bfence
+	UnixNano = bestOrderedRead(x86).unixNano
 
 	return true
 }
 
+// x86Vendor is the CPU vendor got from CPUID leaf 0.
+type x86Vendor uint8
+
+const (
+	vendorOther x86Vendor = iota
+	vendorIntel
+	vendorAMD
+	vendorHygon
+)
+
+// x86Features are the features which decide how to read tsc in order.
+// templexxx/cpu doesn't provide them, so we get them by CPUID directly.
+type x86Features struct {
+	vendor       x86Vendor
+	hasRDTSCP    bool
+	hasRDPID     bool
+	hasSERIALIZE bool
+	// lfenceSerializing indicates LFENCE is dispatch-serializing,
+	// which is always true on Intel.
+	// On AMD, it's only guaranteed by CPUID Fn8000_0021_EAX[2] (LFENCE always serializing),
+	// otherwise it depends on whether the kernel set DE_CFG[1].
+	lfenceSerializing bool
+	// tscFrequency is the TSC frequency (Hz) reported by CPUID leaf 0x15/0x16 or the hypervisor leaf 0x40000010,
+	// 0 means unknown.
+	tscFrequency float64
+}
+
+var x86 = detectX86Features()
+
+func detectX86Features() x86Features {
+	var f x86Features
+
+	maxID, ebx, ecx, edx := cpuid(0, 0)
+
+	switch string(appendUint32s(nil, ebx, edx, ecx)) {
+	case "GenuineIntel":
+		f.vendor = vendorIntel
+	case "AuthenticAMD":
+		f.vendor = vendorAMD
+	case "HygonGenuine":
+		f.vendor = vendorHygon
+	}
+
+	f.tscFrequency = detectTSCFrequency(f.vendor, maxID)
+
+	if maxID >= 7 {
+		_, _, ecx7, edx7 := cpuid(7, 0)
+		f.hasRDPID = ecx7&(1<<22) != 0
+		f.hasSERIALIZE = edx7&(1<<14) != 0
+	}
+
+	maxExtID, _, _, _ := cpuid(0x80000000, 0)
+	if maxExtID >= 0x80000001 {
+		_, _, _, edx81 := cpuid(0x80000001, 0)
+		f.hasRDTSCP = edx81&(1<<27) != 0
+	}
+
+	f.lfenceSerializing = true
+
+	if f.vendor == vendorAMD || f.vendor == vendorHygon {
+		f.lfenceSerializing = false
+
+		if maxExtID >= 0x80000021 {
+			eax821, _, _, _ := cpuid(0x80000021, 0)
+			f.lfenceSerializing = eax821&(1<<2) != 0
+		}
+	}
+
+	return f
+}
+
+// detectTSCFrequency detects the nominal TSC frequency by CPUID.
+func detectTSCFrequency(vendor x86Vendor, maxID uint32) float64 {
+	if maxID >= 0x15 {
+		// TSC frequency = crystal frequency × EBX / EAX.
+		eax, ebx, ecx, _ := cpuid(0x15, 0)
+		if eax != 0 && ebx != 0 && ecx != 0 {
+			return float64(ecx) * float64(ebx) / float64(eax)
+		}
+	}
+
+	// Hypervisors (e.g. VMware, KVM with tsc frequency exposed) report it in kHz.
+	if _, _, ecx1, _ := cpuid(1, 0); ecx1&(1<<31) != 0 {
+		if maxHyp, _, _, _ := cpuid(0x40000000, 0); maxHyp >= 0x40000010 {
+			if khz, _, _, _ := cpuid(0x40000010, 0); khz != 0 {
+				return float64(khz) * 1e3
+			}
+		}
+	}
+
+	// Base frequency in MHz, TSC runs at it on Intel.
+	if vendor == vendorIntel && maxID >= 0x16 {
+		if mhz, _, _, _ := cpuid(0x16, 0); mhz != 0 {
+			return float64(mhz) * 1e6
+		}
+	}
+
+	return 0
+}
+
+// nominalFrequency returns the counter frequency reported by hardware, 0 means unknown.
+func nominalFrequency() float64 {
+	return x86.tscFrequency
+}
+
+func appendUint32s(dst []byte, vs ...uint32) []byte {
+	for _, v := range vs {
+		dst = append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+
+	return dst
+}
+
+func unixNanoCPURDTSCP() (int64, int, int) {
+	ns, aux := unixNanoTSCAux()
+	c, node := decodeTSCAux(aux)
+
+	return ns, c, node
+}
+
+// unixNanoCPUNode is unixNanoCPURDTSCP which applies offset & coeff of the node as UnixNano in per-node offset mode.
+func unixNanoCPUNode() (int64, int, int) {
+	ns, aux := unixNanoTSCNodeAux()
+	c, node := decodeTSCAux(aux)
+
+	return ns, c, node
+}
+
+func unixNanoCPURDPID() (int64, int, int) {
+	ns, aux := unixNanoTSCRDPID()
+	c, node := decodeTSCAux(aux)
+
+	return ns, c, node
+}
+
+// decodeTSCAux decodes TSC_AUX which is set as node<<12 | cpu by Linux.
+func decodeTSCAux(aux uint32) (cpuID, node int) {
+	return int(aux & 0xfff), int(aux >> 12)
+}
+
+// orderedRead is a strategy for reading tsc in strict order.
+type orderedRead struct {
+	name     string
+	read     func() int64 // Counter value in order.
+	unixNano func() int64 // UnixNano in order.
+	usable   func(f x86Features) bool
+}
+
+// orderedReads are all strategies for reading tsc in strict order, in the order of preference,
+// the ones which are not usable on current CPU will be ignored.
+//
+// The order is fixed by what each barrier costs by design instead of timing them at startup,
+// timing is noisy (especially in VMs, where CPUID traps to the hypervisor but may look cheap in a short loop),
+// and a wrong pick makes every UnixNano slow.
+var orderedReads = []orderedRead{
+	{
+		// RDTSCP waits for all previous instructions by itself,
+		// so it's cheaper than LFENCE;RDTSC for the "after" ordering.
+		name:     "rdtscp",
+		read:     getInOrderRDTSCP,
+		unixNano: unixNanoTSC16Brdtscp,
+		usable:   func(f x86Features) bool { return f.hasRDTSCP && f.lfenceSerializing },
+	},
+	{
+		name:     "lfence",
+		read:     getInOrderLFENCE,
+		unixNano: unixNanoTSC16Bfence,
+		usable:   func(f x86Features) bool { return f.lfenceSerializing },
+	},
+	{
+		// MFENCE only orders RDTSC on AMD, Intel SDM asks for LFENCE.
+		name:     "mfence",
+		read:     getInOrderMFENCE,
+		unixNano: unixNanoTSC16Bmfence,
+		usable:   func(f x86Features) bool { return f.vendor == vendorAMD || f.vendor == vendorHygon },
+	},
+	{
+		// SERIALIZE is as strong as CPUID but doesn't trap to the hypervisor.
+		name:     "serialize",
+		read:     getInOrderSerialize,
+		unixNano: unixNanoTSC16Bserialize,
+		usable:   func(f x86Features) bool { return f.hasSERIALIZE },
+	},
+	{
+		// CPUID is always serializing, it's the last resort.
+		name:     "cpuid",
+		read:     getInOrderCPUID,
+		unixNano: unixNanoTSC16Bcpuid,
+		usable:   func(x86Features) bool { return true },
+	},
+}
+
+// usableOrderedReads returns strategies which are valid on current CPU, in the order of preference.
+func usableOrderedReads() []orderedRead {
+	rs := make([]orderedRead, 0, len(orderedReads))
+
+	for _, r := range orderedReads {
+		if r.usable(x86) {
+			rs = append(rs, r)
+		}
+	}
+
+	return rs
+}
+
+// bestOrderedRead returns the most preferred valid strategy on CPU with features f for reading tsc in strict order,
+// see orderedReads for details.
+func bestOrderedRead(f x86Features) orderedRead {
+	for _, r := range orderedReads {
+		if r.usable(f) {
+			return r
+		}
+	}
+
+	// Unreachable, CPUID is always usable.
+	return orderedReads[len(orderedReads)-1]
+}
+
+// hasPerNodeFastPath returns true if node could be got with counter by RDTSCP.
+func hasPerNodeFastPath() bool {
+	return runtime.GOOS == "linux" && x86.hasRDTSCP
+}
+
 func isHardwareSupported() bool {
 	if supported == 1 {
 		return true
@@ -84,71 +334,91 @@ func isHardwareSupported() bool {
 	return true
 }
 
-// Calibrate calibrates tsc clock.
+// GetInOrder gets tsc value in strict order.
+// It's used to help calibrating to avoid out-of-order issues.
 //
-// It's a good practice that runs Calibrate periodically (e.g., 5 min is a good start).
-func Calibrate() {
-	if !isHardwareSupported() {
-		return
-	}
+// It's read with the barrier preferred for the CPU vendor & features (see orderedReads),
+// e.g. LFENCE doesn't order RDTSC on AMD unless it's dispatch-serializing.
+var GetInOrder = bestOrderedRead(x86).read
+
+// getInOrderLFENCE gets tsc value in strict order by LFENCE;RDTSC;LFENCE.
+//
+//go:noescape
+func getInOrderLFENCE() int64
+
+// RDTSC gets tsc value out-of-order.
+//
+//go:noescape
+func RDTSC() int64
+
+//go:noescape
+func unixNanoTSC16B() int64
 
-	cnt := samples
+//go:noescape
+func unixNanoTSCFMA() int64
 
-	tscs := make([]float64, cnt*2)
-	syss := make([]float64, cnt*2)
+//go:noescape
+func unixNanoTSC16Bfence() int64
 
-	for j := range cnt {
-		tsc0, sys0 := getClosestTSCSys(getClosestTSCSysRetries)
+//go:noescape
+func unixNanoTSC16Brdtscp() int64
 
-		time.Sleep(sampleDuration)
+//go:noescape
+func unixNanoTSC16Bmfence() int64
 
-		tsc1, sys1 := getClosestTSCSys(getClosestTSCSysRetries)
+//go:noescape
+func unixNanoTSC16Bserialize() int64
 
-		tscs[j*2] = float64(tsc0)
-		tscs[j*2+1] = float64(tsc1)
+//go:noescape
+func unixNanoTSC16Bcpuid() int64
 
-		syss[j*2] = float64(sys0)
-		syss[j*2+1] = float64(sys1)
-	}
+// unixNanoTSCNode applies offset & coeff of the node where tsc is read (by RDTSCP).
+//
+//go:noescape
+func unixNanoTSCNode() int64
 
-	coeff, offset := simpleLinearRegression(tscs, syss)
-	storeOffsetCoeff(OffsetCoeffAddr, offset, coeff)
-	storeOffsetFCoeff(OffsetCoeffFAddr, float64(offset), coeff)
-}
+// unixNanoTSCNodeFence is unixNanoTSCNode in strict order.
+//
+//go:noescape
+func unixNanoTSCNodeFence() int64
 
-// CalibrateWithCoeff calibrates coefficient to wall_clock by variables.
+// unixNanoTSCAux returns UnixNano & TSC_AUX by RDTSCP.
 //
-// Not thread safe, only for testing.
-func CalibrateWithCoeff(coeff float64) {
-	if !Supported() {
-		return
-	}
+//go:noescape
+func unixNanoTSCAux() (ns int64, aux uint32)
 
-	tsc, sys := getClosestTSCSys(getClosestTSCSysRetries)
-	off := sys - int64(float64(tsc)*coeff)
-	storeOffsetCoeff(OffsetCoeffAddr, off, coeff)
-	storeOffsetFCoeff(OffsetCoeffFAddr, float64(off), coeff)
-}
+// unixNanoTSCNodeAux is unixNanoTSCAux which applies offset & coeff of the node where tsc is read.
+//
+//go:noescape
+func unixNanoTSCNodeAux() (ns int64, aux uint32)
 
-// GetInOrder gets tsc value in strict order.
-// It's used to help calibrating to avoid out-of-order issues.
+// unixNanoTSCRDPID returns UnixNano & TSC_AUX by RDPID;RDTSC.
 //
 //go:noescape
-func GetInOrder() int64
+func unixNanoTSCRDPID() (ns int64, aux uint32)
 
-// RDTSC gets tsc value out-of-order.
+// getInOrderRDTSCP gets tsc value in strict order by RDTSCP;LFENCE.
 //
 //go:noescape
-func RDTSC() int64
+func getInOrderRDTSCP() int64
 
+// getInOrderMFENCE gets tsc value in strict order by MFENCE;RDTSC;MFENCE.
+//
 //go:noescape
-func unixNanoTSC16B() int64
+func getInOrderMFENCE() int64
 
+// getInOrderSerialize gets tsc value in strict order by SERIALIZE;RDTSC;SERIALIZE.
+//
 //go:noescape
-func unixNanoTSCFMA() int64
+func getInOrderSerialize() int64
 
+// getInOrderCPUID gets tsc value in strict order by CPUID;RDTSC;CPUID.
+//
 //go:noescape
-func unixNanoTSC16Bfence() int64
+func getInOrderCPUID() int64
+
+//go:noescape
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
 
 //go:noescape
 func storeOffsetCoeff(dst *byte, offset int64, coeff float64)
@@ -1,7 +1,7 @@
 #include "textflag.h"
 
-// func GetInOrder() int64
-TEXT ·GetInOrder(SB), NOSPLIT, $0
+// func getInOrderLFENCE() int64
+TEXT ·getInOrderLFENCE(SB), NOSPLIT, $0
 
 	LFENCE             // Ensure all previous instructions have exectuted.
 	RDTSC
@@ -11,6 +11,63 @@ TEXT ·GetInOrder(SB), NOSPLIT, $0
 	MOVQ AX, ret+0(FP)
 	RET
 
+// func getInOrderRDTSCP() int64
+TEXT ·getInOrderRDTSCP(SB), NOSPLIT, $0
+
+	RDTSCP             // Waits until all previous instructions have executed.
+	LFENCE             // Ensure RDTSCP to be exectued prior to exection of any subsequent instruction.
+	SALQ $32, DX
+	ORQ  DX, AX
+	MOVQ AX, ret+0(FP)
+	RET
+
+// func getInOrderMFENCE() int64
+TEXT ·getInOrderMFENCE(SB), NOSPLIT, $0
+
+	MFENCE             // AMD recommended barrier for RDTSC when LFENCE isn't dispatch-serializing.
+	RDTSC
+	MFENCE
+	SALQ $32, DX
+	ORQ  DX, AX
+	MOVQ AX, ret+0(FP)
+	RET
+
+// func getInOrderSerialize() int64
+TEXT ·getInOrderSerialize(SB), NOSPLIT, $0
+
+	BYTE $0x0f; BYTE $0x01; BYTE $0xe8 // SERIALIZE
+	RDTSC
+	BYTE $0x0f; BYTE $0x01; BYTE $0xe8 // SERIALIZE
+	SALQ $32, DX
+	ORQ  DX, AX
+	MOVQ AX, ret+0(FP)
+	RET
+
+// func getInOrderCPUID() int64
+TEXT ·getInOrderCPUID(SB), NOSPLIT, $0
+
+	XORL AX, AX
+	CPUID              // Serializing instruction, but very slow (it causes VM exit in virtual machine).
+	RDTSC
+	SALQ $32, DX
+	ORQ  DX, AX
+	MOVQ AX, R8        // CPUID overwrites AX, BX, CX, DX.
+	XORL AX, AX
+	CPUID
+	MOVQ R8, ret+0(FP)
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
 // func RDTSC() int64
 TEXT ·RDTSC(SB), NOSPLIT, $0
 
@@ -33,8 +90,7 @@ TEXT ·unixNanoTSC16B(SB), NOSPLIT, $0
 	ORQ  DX, AX  // -> [DX, tsc] (high, low)
 
 	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
-	MOVQ        ·OffsetCoeffAddr(SB), BX
-	VMOVDQA     (BX), X3
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
 	VMULSD      X3, X0, X0               // ns = coeff * ftsc
 	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
 	VMOVHLPS    X3, X3, X3
@@ -56,8 +112,7 @@ TEXT ·unixNanoTSCFMA(SB), NOSPLIT, $0
 	ORQ  DX, AX  // -> [DX, tsc] (high, low)
 
 	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
-	MOVQ        ·OffsetCoeffFAddr(SB), BX
-	VMOVDQA     (BX), X3    // get coeff
+	VMOVDQA     ·params+48(SB), X3       // get coeff
 	VMOVHLPS    X3, X3, X4 // get offset
 	VFMADD132PD X0, X4, X3  // X0 * X3 + X4 -> X3: ftsc * coeff + offset
 	VCVTTSD2SIQ X3, AX
@@ -74,8 +129,63 @@ TEXT ·unixNanoTSC16Bfence(SB), NOSPLIT, $0
 	ORQ  DX, AX  // -> [DX, tsc] (high, low)
 
 	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
-	MOVQ        ·OffsetCoeffAddr(SB), BX
-	VMOVDQA     (BX), X3    // get coeff
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSC16Brdtscp() int64
+TEXT ·unixNanoTSC16Brdtscp(SB), NOSPLIT, $0
+
+	RDTSCP       // high 32bit in DX, low 32bit in AX (tsc), TSC_AUX in CX.
+	LFENCE
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSC16Bmfence() int64
+TEXT ·unixNanoTSC16Bmfence(SB), NOSPLIT, $0
+
+	MFENCE
+	RDTSC        // high 32bit in DX, low 32bit in AX (tsc).
+	MFENCE
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSC16Bserialize() int64
+TEXT ·unixNanoTSC16Bserialize(SB), NOSPLIT, $0
+
+	BYTE $0x0f; BYTE $0x01; BYTE $0xe8 // SERIALIZE
+	RDTSC        // high 32bit in DX, low 32bit in AX (tsc).
+	BYTE $0x0f; BYTE $0x01; BYTE $0xe8 // SERIALIZE
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
 	VMULSD      X3, X0, X0               // ns = coeff * ftsc
 	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
 	VMOVHLPS    X3, X3, X3
@@ -84,6 +194,136 @@ TEXT ·unixNanoTSC16Bfence(SB), NOSPLIT, $0
 	MOVQ        AX, ret+0(FP)
 	RET
 
+// func unixNanoTSC16Bcpuid() int64
+TEXT ·unixNanoTSC16Bcpuid(SB), NOSPLIT, $0
+
+	XORL AX, AX
+	CPUID
+	RDTSC        // high 32bit in DX, low 32bit in AX (tsc).
+	MOVL AX, R8
+	MOVL DX, R9
+	XORL AX, AX
+	CPUID
+	MOVL R8, AX
+	MOVL R9, DX
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSCNode() int64
+TEXT ·unixNanoTSCNode(SB), NOSPLIT, $0
+
+	RDTSCP       // high 32bit in DX, low 32bit in AX (tsc), TSC_AUX in CX.
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+	SHRL $12, CX // node = TSC_AUX >> 12
+	ANDL $63, CX // maxNodes-1, avoid out of range
+	SHLQ $6, CX  // node * CacheLineSize
+
+	VCVTSI2SDQ  AX, X0, X0                   // ftsc = float64(tsc)
+	LEAQ        ·nodeParams+32(SB), BX       // no memory access, just address
+	VMOVDQA     (BX)(CX*1), X3               // get coeff of node
+	VMULSD      X3, X0, X0                   // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                       // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                       // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSCNodeFence() int64
+TEXT ·unixNanoTSCNodeFence(SB), NOSPLIT, $0
+
+	RDTSCP       // high 32bit in DX, low 32bit in AX (tsc), TSC_AUX in CX.
+	LFENCE
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+	SHRL $12, CX // node = TSC_AUX >> 12
+	ANDL $63, CX // maxNodes-1, avoid out of range
+	SHLQ $6, CX  // node * CacheLineSize
+
+	VCVTSI2SDQ  AX, X0, X0                   // ftsc = float64(tsc)
+	LEAQ        ·nodeParams+32(SB), BX       // no memory access, just address
+	VMOVDQA     (BX)(CX*1), X3               // get coeff of node
+	VMULSD      X3, X0, X0                   // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                       // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                       // un += offset
+	MOVQ        AX, ret+0(FP)
+	RET
+
+// func unixNanoTSCNodeAux() (ns int64, aux uint32)
+TEXT ·unixNanoTSCNodeAux(SB), NOSPLIT, $0
+
+	RDTSCP       // high 32bit in DX, low 32bit in AX (tsc), TSC_AUX in CX.
+	MOVL CX, R8
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+	SHRL $12, CX // node = TSC_AUX >> 12
+	ANDL $63, CX // maxNodes-1, avoid out of range
+	SHLQ $6, CX  // node * CacheLineSize
+
+	VCVTSI2SDQ  AX, X0, X0                   // ftsc = float64(tsc)
+	LEAQ        ·nodeParams+32(SB), BX       // no memory access, just address
+	VMOVDQA     (BX)(CX*1), X3               // get coeff of node
+	VMULSD      X3, X0, X0                   // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                       // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                       // un += offset
+	MOVQ        AX, ns+0(FP)
+	MOVL        R8, aux+8(FP)
+	RET
+
+// func unixNanoTSCAux() (ns int64, aux uint32)
+TEXT ·unixNanoTSCAux(SB), NOSPLIT, $0
+
+	RDTSCP       // high 32bit in DX, low 32bit in AX (tsc), TSC_AUX in CX.
+	MOVL CX, R8
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ns+0(FP)
+	MOVL        R8, aux+8(FP)
+	RET
+
+// func unixNanoTSCRDPID() (ns int64, aux uint32)
+TEXT ·unixNanoTSCRDPID(SB), NOSPLIT, $0
+
+	BYTE $0xf3; BYTE $0x0f; BYTE $0xc7; BYTE $0xf8 // RDPID AX (TSC_AUX)
+	MOVL AX, R8
+	RDTSC        // high 32bit in DX, low 32bit in AX (tsc).
+	SALQ $32, DX
+	ORQ  DX, AX  // -> [DX, tsc] (high, low)
+
+	VCVTSI2SDQ  AX, X0, X0               // ftsc = float64(tsc)
+	VMOVDQA     ·params+32(SB), X3       // get coeff (offset in high 64bit)
+	VMULSD      X3, X0, X0               // ns = coeff * ftsc
+	VCVTTSD2SIQ X0, AX                   // un = int64(ns)
+	VMOVHLPS    X3, X3, X3
+	VMOVQ       X3, CX
+	ADDQ        CX, AX                   // un += offset
+	MOVQ        AX, ns+0(FP)
+	MOVL        R8, aux+8(FP)
+	RET
+
 // func loadOffsetCoeff(src *byte) (offset int64, coeff float64)
 TEXT ·LoadOffsetCoeff(SB), NOSPLIT, $0
 	MOVQ     src+0(FP), AX
@@ -4,8 +4,12 @@ package tsc
 
 import (
 	"math/rand"
+	"reflect"
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/templexxx/cpu"
 	"github.com/templexxx/tsc/internal/xbytes"
 )
 
@@ -62,6 +66,279 @@ func TestGetInOrder(t *testing.T) {
 	}
 }
 
+// Out-of-Order test for all usable strategies, they should be in order as we assume.
+func TestOrderedReads(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	n := 4096
+	ret0 := make([]int64, n)
+	ret1 := make([]int64, n)
+
+	for _, r := range usableOrderedReads() {
+		for i := range ret0 {
+			ret0[i] = r.read()
+			ret1[i] = r.read()
+		}
+
+		cnt := 0
+
+		for i := range n {
+			d := ret1[i] - ret0[i]
+			if d < 0 {
+				cnt++
+			}
+		}
+
+		if cnt > 0 {
+			t.Fatalf("%s is not in order: %d aren't in order", r.name, cnt)
+		}
+	}
+}
+
+func TestBestOrderedRead(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		f    x86Features
+		exp  string
+	}{
+		{"intel", x86Features{vendor: vendorIntel, hasRDTSCP: true, lfenceSerializing: true}, "rdtscp"},
+		{"intel without rdtscp", x86Features{vendor: vendorIntel, lfenceSerializing: true}, "lfence"},
+		{"amd", x86Features{vendor: vendorAMD, hasRDTSCP: true, lfenceSerializing: true}, "rdtscp"},
+		{"amd without serializing lfence", x86Features{vendor: vendorAMD, hasRDTSCP: true, hasSERIALIZE: true}, "mfence"},
+		{"serialize", x86Features{hasRDTSCP: true, hasSERIALIZE: true}, "serialize"},
+		{"nothing", x86Features{hasRDTSCP: true}, "cpuid"},
+	} {
+		if got := bestOrderedRead(tc.f).name; got != tc.exp {
+			t.Fatalf("%s: exp: %s, got: %s", tc.name, tc.exp, got)
+		}
+	}
+
+	// Preference doesn't depend on timing, the same features always pick the same strategy.
+	best := bestOrderedRead(x86)
+	if got, exp := best.name, usableOrderedReads()[0].name; got != exp {
+		t.Fatalf("exp: %s, got: %s", exp, got)
+	}
+
+	// GetInOrder (and NowTicks by it) uses the preferred barrier too.
+	if reflect.ValueOf(GetInOrder).Pointer() != reflect.ValueOf(best.read).Pointer() {
+		t.Fatalf("GetInOrder should read by %s", best.name)
+	}
+}
+
+func TestOrderedReadsUnixNano(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	for _, r := range usableOrderedReads() {
+		exp := unixNanoTSC16B()
+		got := r.unixNano()
+
+		// Cost of CPUID could be several µs in VM.
+		if got < exp || got-exp > 1000000 {
+			t.Fatalf("%s got unexpected unix nano, exp: >= %d, got: %d", r.name, exp, got)
+		}
+	}
+}
+
+func BenchmarkOrderedReads(b *testing.B) {
+	if !Supported() {
+		b.Skip("tsc is unsupported")
+	}
+
+	for _, r := range usableOrderedReads() {
+		b.Run(r.name, func(b *testing.B) {
+			for range b.N {
+				_ = r.unixNano()
+			}
+		})
+	}
+}
+
+func TestUnixNanoTSCAux(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	if runtime.GOOS != "linux" {
+		t.Skip("TSC_AUX is only set by Linux")
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	reads := map[string]func() (int64, int, int){}
+	if x86.hasRDTSCP {
+		reads["rdtscp"] = unixNanoCPURDTSCP
+	}
+
+	if x86.hasRDPID {
+		reads["rdpid"] = unixNanoCPURDPID
+	}
+
+	for name, read := range reads {
+		// Thread may be migrated between two calls, retry for getting the same location.
+		matched := false
+
+		for range 100 {
+			expCPU, expNode := getcpu()
+			_, cpu, node := read()
+
+			if cpu == expCPU && node == expNode {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			t.Fatalf("%s got location mismatched with getcpu", name)
+		}
+	}
+}
+
+func TestUnixNanoTSCNode(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() || !hasPerNodeFastPath() {
+		t.Skip("per-node offset is unsupported")
+	}
+
+	if raceDetectorEnabled {
+		t.Skip("race detector affects timing accuracy")
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+	ns := measurePerNode(new(sampler), coeff)
+
+	publishMu.Lock()
+	storePerNode(ns, GetInOrder(), offset, coeff, 0)
+	publishMu.Unlock()
+
+	for _, read := range []func() int64{unixNanoTSCNode, unixNanoTSCNodeFence} {
+		var total int64
+
+		for range 10 {
+			total += read() - time.Now().UnixNano()
+		}
+
+		if avg := total / 10; avg > 50000 || avg < -50000 {
+			t.Fatalf("per-node clock is too far away from system clock: %d ns", avg)
+		}
+	}
+}
+
+//nolint:paralleltest // Changes published & per-node parameters.
+func TestPerNodePublish(t *testing.T) {
+	if !Supported() || !hasPerNodeFastPath() {
+		t.Skip("per-node offset is unsupported")
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	// Makes nodes distinguishable from the global offset.
+	const nodeDelta = int64(time.Second)
+
+	for i := range maxNodes {
+		storeOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize], offset+nodeDelta, coeff)
+	}
+
+	perNodeOffset = 1
+
+	defer func() {
+		perNodeOffset = 0
+		publish(offset, coeff)
+	}()
+
+	// UnixNanoCPU agrees with UnixNano in per-node offset mode.
+	before := unixNanoTSCNodeFence()
+	ns, _, _ := unixNanoCPUNode()
+	after := unixNanoTSCNodeFence()
+
+	// Out-of-order reading.
+	const tolerance = 1000
+	if ns < before-tolerance || ns > after+tolerance {
+		t.Fatalf("per-node UnixNanoCPU mismatch: %d not in [%d, %d]", ns, before, after)
+	}
+
+	// publish moves per-node offsets with the global one instead of sampling again.
+	publish(offset+100, coeff)
+
+	for i := range maxNodes {
+		if got, _ := LoadOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize]); got != offset+100+nodeDelta {
+			t.Fatalf("node %d offset mismatch: exp: %d, got: %d", i, offset+100+nodeDelta, got)
+		}
+	}
+}
+
+//nolint:paralleltest // Changes per-node parameters.
+func TestStorePerNode(t *testing.T) {
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	const nodeDelta = int64(time.Second)
+
+	for i := range maxNodes {
+		storeOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize], offset+nodeDelta+int64(i), coeff)
+	}
+
+	// The last node has never been calibrated.
+	storeOffsetCoeff(&NodeOffsetCoeff[(maxNodes-1)*CacheLineSize], 0, 0)
+
+	defer func() {
+		for i := range maxNodes {
+			storeOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize], offset, coeff)
+		}
+	}()
+
+	// Only node 0 is measured.
+	ns := new(nodeOffsets)
+	ns.offset[0], ns.ok[0] = offset+7, true
+
+	publishMu.Lock()
+	storePerNode(ns, GetInOrder(), offset, coeff, 100)
+	publishMu.Unlock()
+
+	for i := range maxNodes {
+		// Nodes which aren't measured keep their corrections, moved with the global conversion.
+		exp := offset + nodeDelta + int64(i) + 100
+
+		switch i {
+		case 0:
+			exp = offset + 7
+		case maxNodes - 1:
+			exp = offset
+		}
+
+		if got, _ := LoadOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize]); got != exp {
+			t.Fatalf("node %d offset mismatch: exp: %d, got: %d", i, exp, got)
+		}
+	}
+}
+
+func BenchmarkUnixNanoTSCNode(b *testing.B) {
+	if !Supported() || !hasPerNodeFastPath() {
+		b.Skip("per-node offset is unsupported")
+	}
+
+	for range b.N {
+		_ = unixNanoTSCNode()
+	}
+}
+
 func BenchmarkGetInOrder(b *testing.B) {
 	if !Supported() {
 		b.Skip("tsc is unsupported")
@@ -101,3 +378,88 @@ func BenchmarkUnixNanoTSC16B(b *testing.B) {
 		_ = unixNanoTSC16B()
 	}
 }
+
+func TestSnapshotBrackets(t *testing.T) {
+	t.Parallel()
+
+	if !Supported() {
+		t.Skip("tsc is unsupported")
+	}
+
+	snapMul := func() Snapshot {
+		s := CurrentSnapshot()
+		s.fma = false
+
+		return s
+	}
+
+	checkSnapshotBrackets(t, "16B", snapMul, unixNanoTSC16B)
+
+	if cpu.X86.HasFMA {
+		checkSnapshotBrackets(t, "FMA", func() Snapshot {
+			s := CurrentSnapshot()
+			s.fma = true
+
+			return s
+		}, unixNanoTSCFMA)
+	}
+
+	for _, r := range usableOrderedReads() {
+		checkSnapshotBrackets(t, r.name, snapMul, r.unixNano)
+	}
+
+	if x86.hasRDTSCP {
+		checkSnapshotBrackets(t, "rdtscp aux", snapMul, func() int64 {
+			ns, _ := unixNanoTSCAux()
+
+			return ns
+		})
+	}
+
+	if x86.hasRDPID {
+		checkSnapshotBrackets(t, "rdpid", snapMul, func() int64 {
+			ns, _ := unixNanoTSCRDPID()
+
+			return ns
+		})
+	}
+}
+
+//nolint:paralleltest // Changes per-node parameters.
+func TestSnapshotBracketsPerNode(t *testing.T) {
+	if !Supported() || !hasPerNodeFastPath() {
+		t.Skip("per-node offset is unsupported")
+	}
+
+	offset, coeff := LoadOffsetCoeff(OffsetCoeffAddr)
+
+	// All nodes share an offset which is distinguishable from the global one,
+	// so the snapshot of nodes is the global one moved by nodeDelta.
+	const nodeDelta = int64(time.Second)
+
+	for i := range maxNodes {
+		storeOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize], offset+nodeDelta, coeff)
+	}
+
+	defer func() {
+		for i := range maxNodes {
+			storeOffsetCoeff(&NodeOffsetCoeff[i*CacheLineSize], offset, coeff)
+		}
+	}()
+
+	snapNode := func() Snapshot {
+		s := CurrentSnapshot()
+		s.offset += nodeDelta
+		s.fma = false
+
+		return s
+	}
+
+	checkSnapshotBrackets(t, "node", snapNode, unixNanoTSCNode)
+	checkSnapshotBrackets(t, "node fenced", snapNode, unixNanoTSCNodeFence)
+	checkSnapshotBrackets(t, "node aux", snapNode, func() int64 {
+		ns, _, _ := unixNanoCPUNode()
+
+		return ns
+	})
+}
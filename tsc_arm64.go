@@ -3,19 +3,26 @@
 package tsc
 
 import (
-	"time"
+	"encoding/binary"
+	"os"
+	"runtime"
 )
 
-// Configs of calibration.
-// See tools/calibrate for details.
+// ARM64FalseSharingRange is the cache line size on ARM64 (typically 64 bytes)
+const ARM64FalseSharingRange = 64
+
+// Linux auxiliary vector keys & bits for detecting FEAT_ECV.
+// See arch/arm64/include/uapi/asm/hwcap.h in Linux.
 const (
-	samples                 = 128
-	sampleDuration          = 16 * time.Millisecond
-	getClosestTSCSysRetries = 256
+	atHWCAP    = 16
+	atHWCAP2   = 26
+	hwcapCPUID = 1 << 11
+	hwcap2ECV  = 1 << 19
 )
 
-// ARM64FalseSharingRange is the cache line size on ARM64 (typically 64 bytes)
-const ARM64FalseSharingRange = 64
+// hasECV indicates FEAT_ECV (Armv8.6 Enhanced Counter Virtualization) is supported,
+// which provides the self-synchronized counter view CNTVCTSS_EL0.
+var hasECV = detectECV()
 
 func init() {
 	_ = reset()
@@ -26,7 +33,15 @@ func reset() bool {
 		return false
 	}
 
-	Calibrate()
+	fmaConversion = false
+
+	// If the calibration is rejected, the published parameters may be none (zero) on the first one,
+	// the system clock is used instead.
+	if _, err := CalibrateWithOptions(CalibrationOptions{}); err != nil {
+		UnixNano = sysClock
+		UnixNanoCPU = unixNanoGetcpu
+		return false
+	}
 
 	if IsOutOfOrder() {
 		// Try to determine if FMADD variant is faster
@@ -44,16 +59,34 @@ func reset() bool {
 
 		if fmaCost < armCost {
 			UnixNano = unixNanoARMFMADD
+			fmaConversion = true
 		} else {
 			UnixNano = unixNanoARM16B
 		}
 		return true
 	}
 
+	// CNTVCTSS_EL0 is self-synchronizing, so no ISB is needed to keep the read in order.
+	if hasECV {
+		UnixNano = unixNanoARM16BECV
+		return true
+	}
+
 	UnixNano = unixNanoARM16Bfence
 	return true
 }
 
+// hasPerNodeFastPath returns false, because the Generic Timer is system-wide by architecture,
+// and there is no way to read counter & node together.
+func hasPerNodeFastPath() bool {
+	return false
+}
+
+// nominalFrequency returns the counter frequency reported by CNTFRQ_EL0, 0 means unknown.
+func nominalFrequency() float64 {
+	return float64(readCounterFrequency())
+}
+
 func isHardwareSupported() bool {
 	if supported == 1 {
 		return true
@@ -75,68 +108,82 @@ func isHardwareSupported() bool {
 	return true
 }
 
-// Calibrate calibrates the ARM Generic Timer clock using hybrid approach.
+// detectECV detects FEAT_ECV by HWCAP2 in /proc/self/auxv,
+// falling back to ID_AA64MMFR0_EL1 when the kernel emulates ID register access (HWCAP_CPUID).
 //
-// It's a good practice that runs Calibrate periodically (e.g., 5 min is a good start).
-func Calibrate() {
-	if !isHardwareSupported() {
-		return
+// Only works on Linux, returns false on other platforms.
+func detectECV() bool {
+	if runtime.GOOS != "linux" {
+		return false
 	}
 
-	// Hybrid approach: Use frequency from hardware, refine with linear regression
-	// The ARM64 Generic Timer provides frequency via CNTFRQ_EL0
-	// We use linear regression to measure actual drift and get accurate offset
-
-	// Run linear regression calibration for accuracy
-	cnt := samples
-
-	tscs := make([]float64, cnt*2)
-	syss := make([]float64, cnt*2)
-
-	for j := 0; j < cnt; j++ {
-		tsc0, sys0 := getClosestTSCSys(getClosestTSCSysRetries)
-		time.Sleep(sampleDuration)
-		tsc1, sys1 := getClosestTSCSys(getClosestTSCSysRetries)
+	d, err := os.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return false
+	}
 
-		tscs[j*2] = float64(tsc0)
-		tscs[j*2+1] = float64(tsc1)
+	var hwcap, hwcap2 uint64
+	for i := 0; i+16 <= len(d); i += 16 {
+		tag := binary.LittleEndian.Uint64(d[i:])
+		val := binary.LittleEndian.Uint64(d[i+8:])
+		switch tag {
+		case atHWCAP:
+			hwcap = val
+		case atHWCAP2:
+			hwcap2 = val
+		}
+	}
 
-		syss[j*2] = float64(sys0)
-		syss[j*2+1] = float64(sys1)
+	if hwcap2&hwcap2ECV != 0 {
+		return true
 	}
 
-	coeff, offset := simpleLinearRegression(tscs, syss)
+	// Older kernels don't report ECV in HWCAP2, but the ID register tells the truth.
+	// ECV is in bits [63:60] of ID_AA64MMFR0_EL1, 0 means not implemented.
+	if hwcap&hwcapCPUID != 0 {
+		return readMMFR0()>>60 != 0
+	}
 
-	// Store calibrated values
-	storeOffsetCoeff(OffsetCoeffAddr, offset, coeff)
-	storeOffsetFCoeff(OffsetCoeffFAddr, float64(offset), coeff)
+	return false
 }
 
-// CalibrateWithCoeff calibrates coefficient to wall_clock by variables.
+// GetInOrder gets counter value in strict order.
+// It's used to help calibrating to avoid out-of-order issues.
 //
-// Not thread safe, only for testing.
-func CalibrateWithCoeff(c float64) {
-	if !Supported() {
-		return
+// It reads the self-synchronized counter view (CNTVCTSS_EL0) without barriers if FEAT_ECV is supported,
+// or the counter between ISBs otherwise.
+var GetInOrder = orderedRead()
+
+// orderedRead returns the cheapest strict order read of the counter.
+func orderedRead() func() int64 {
+	if hasECV {
+		return getInOrderECV
 	}
-
-	tsc, sys := getClosestTSCSys(getClosestTSCSysRetries)
-	off := sys - int64(float64(tsc)*c)
-	storeOffsetCoeff(OffsetCoeffAddr, off, c)
-	storeOffsetFCoeff(OffsetCoeffFAddr, float64(off), c)
+	return getInOrderISB
 }
 
-// GetInOrder gets counter value in strict order.
-// It's used to help calibrating to avoid out-of-order issues.
+// getInOrderISB gets counter value in strict order by ISB;MRS CNTVCT_EL0;ISB.
 //
 //go:noescape
-func GetInOrder() int64
+func getInOrderISB() int64
 
 // RDTSC gets counter value out-of-order (fast path).
 //
 //go:noescape
 func RDTSC() int64
 
+// getInOrderECV gets counter value in strict order by the self-synchronized counter view (CNTVCTSS_EL0).
+// Only available when hasECV is true, it's GetInOrder then.
+//
+//go:noescape
+func getInOrderECV() int64
+
+// readMMFR0 reads the ID_AA64MMFR0_EL1 register.
+// Only available when the kernel emulates ID register access (HWCAP_CPUID).
+//
+//go:noescape
+func readMMFR0() uint64
+
 // readCounterFrequency reads the CNTFRQ_EL0 register.
 //
 //go:noescape
@@ -151,6 +198,9 @@ func unixNanoARMFMADD() int64
 //go:noescape
 func unixNanoARM16Bfence() int64
 
+//go:noescape
+func unixNanoARM16BECV() int64
+
 //go:noescape
 func storeOffsetCoeff(dst *byte, offset int64, coeff float64)
 
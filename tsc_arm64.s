@@ -2,8 +2,8 @@
 
 #include "textflag.h"
 
-// func GetInOrder() int64
-TEXT ·GetInOrder(SB), NOSPLIT, $0-8
+// func getInOrderISB() int64
+TEXT ·getInOrderISB(SB), NOSPLIT, $0-8
 	// ISB ensures all previous instructions have executed
 	WORD $0xD5033FDF  // ISB
 
@@ -24,6 +24,23 @@ TEXT ·RDTSC(SB), NOSPLIT, $0-8
 	MOVD R0, ret+0(FP)
 	RET
 
+// func getInOrderECV() int64
+TEXT ·getInOrderECV(SB), NOSPLIT, $0-8
+	// CNTVCTSS_EL0 (FEAT_ECV) is self-synchronizing:
+	// it can't be read speculatively or out of order, so no ISB is needed.
+	WORD $0xD53BE0C0  // MRS CNTVCTSS_EL0, R0
+
+	MOVD R0, ret+0(FP)
+	RET
+
+// func readMMFR0() uint64
+TEXT ·readMMFR0(SB), NOSPLIT, $0-8
+	// Trapped & emulated by Linux when HWCAP_CPUID is set
+	WORD $0xD5380700  // MRS ID_AA64MMFR0_EL1, R0
+
+	MOVD R0, ret+0(FP)
+	RET
+
 // func readCounterFrequency() int64
 TEXT ·readCounterFrequency(SB), NOSPLIT, $0-8
 	// Read the counter frequency register (CNTFRQ_EL0)
@@ -37,12 +54,9 @@ TEXT ·unixNanoARM16B(SB), NOSPLIT, $0-8
 	// Read counter without barriers (fast path)
 	WORD $0xD53BE040  // MRS CNTVCT_EL0, R0
 
-	// Load offset and coefficient from memory
-	MOVD ·OffsetCoeffAddr(SB), R1
-
-	// Load coeff from [R1] and offset from [R1+8]
-	FMOVD (R1), F0
-	MOVD 8(R1), R2
+	// Load coeff and offset from the static block directly
+	FMOVD ·params+32(SB), F0
+	MOVD ·params+40(SB), R2
 
 	// Convert counter to float64 (unsigned)
 	WORD $0x9E630001  // UCVTF D1, X0 (unsigned conversion)
@@ -64,12 +78,9 @@ TEXT ·unixNanoARMFMADD(SB), NOSPLIT, $0-8
 	// Read counter without barriers
 	WORD $0xD53BE040  // MRS CNTVCT_EL0, R0
 
-	// Load offset and coefficient
-	MOVD ·OffsetCoeffFAddr(SB), R1
-
-	// Load coeff from [R1] and offset from [R1+8]
-	FMOVD (R1), F0    // coeff
-	FMOVD 8(R1), F2   // offset
+	// Load coeff and offset from the static block directly
+	FMOVD ·params+48(SB), F0   // coeff
+	FMOVD ·params+56(SB), F2   // offset
 
 	// Convert counter to float64 (unsigned)
 	WORD $0x9E630001  // UCVTF D1, X0 (unsigned conversion)
@@ -94,12 +105,33 @@ TEXT ·unixNanoARM16Bfence(SB), NOSPLIT, $0-8
 	// ISB after reading counter
 	WORD $0xD5033FDF  // ISB
 
-	// Load offset and coefficient
-	MOVD ·OffsetCoeffAddr(SB), R1
+	// Load coeff and offset from the static block directly
+	FMOVD ·params+32(SB), F0
+	MOVD ·params+40(SB), R2
+
+	// Convert counter to float64 (unsigned)
+	WORD $0x9E630001  // UCVTF D1, X0 (unsigned conversion)
+
+	// Multiply: ns = coeff * counter
+	WORD $0x1E600820  // FMULD D0, D1, D0
+
+	// Convert to int64
+	WORD $0x9E780000  // FCVTZS D0, X0
+
+	// Add offset
+	ADD R2, R0
+
+	MOVD R0, ret+0(FP)
+	RET
+
+// func unixNanoARM16BECV() int64
+TEXT ·unixNanoARM16BECV(SB), NOSPLIT, $0-8
+	// Read self-synchronized counter, no ISB needed
+	WORD $0xD53BE0C0  // MRS CNTVCTSS_EL0, R0
 
-	// Load coeff from [R1] and offset from [R1+8]
-	FMOVD (R1), F0
-	MOVD 8(R1), R2
+	// Load coeff and offset from the static block directly
+	FMOVD ·params+32(SB), F0
+	MOVD ·params+40(SB), R2
 
 	// Convert counter to float64 (unsigned)
 	WORD $0x9E630001  // UCVTF D1, X0 (unsigned conversion)
@@ -5,6 +5,7 @@ package tsc
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"testing"
 	"time"
 
@@ -57,6 +58,40 @@ func TestGetInOrder(t *testing.T) {
 	}
 }
 
+// Out-of-Order test for the self-synchronized counter view.
+func TestGetInOrderECV(t *testing.T) {
+	if !Supported() {
+		t.Skip("Generic Timer is unsupported")
+	}
+	if !hasECV {
+		t.Skip("FEAT_ECV is unsupported")
+	}
+
+	n := 4096
+	ret0 := make([]int64, n)
+	ret1 := make([]int64, n)
+
+	for i := range ret0 {
+		ret0[i] = getInOrderECV()
+		ret1[i] = getInOrderECV()
+	}
+
+	cnt := 0
+	for i := 0; i < n; i++ {
+		d := ret1[i] - ret0[i]
+		if d < 0 {
+			cnt++
+		}
+	}
+	if cnt > 0 {
+		t.Fatal(fmt.Sprintf("getInOrderECV is not in order: %d aren't in order", cnt))
+	}
+	// GetInOrder (and NowTicks & calibration by it) uses the ECV read too.
+	if reflect.ValueOf(GetInOrder).Pointer() != reflect.ValueOf(getInOrderECV).Pointer() {
+		t.Fatal("GetInOrder should read CNTVCTSS_EL0 with FEAT_ECV")
+	}
+}
+
 func TestReadCounterFrequency(t *testing.T) {
 	if !Supported() {
 		t.Skip("Generic Timer is unsupported")
@@ -118,3 +153,38 @@ func BenchmarkUnixNanoARM16Bfence(b *testing.B) {
 		_ = unixNanoARM16Bfence()
 	}
 }
+
+func BenchmarkUnixNanoARM16BECV(b *testing.B) {
+	if !Supported() {
+		b.Skip("Generic Timer is unsupported")
+	}
+	if !hasECV {
+		b.Skip("FEAT_ECV is unsupported")
+	}
+
+	for i := 0; i < b.N; i++ {
+		_ = unixNanoARM16BECV()
+	}
+}
+
+func TestSnapshotBrackets(t *testing.T) {
+	if !Supported() {
+		t.Skip("Generic Timer is unsupported")
+	}
+
+	snap := func(fma bool) func() Snapshot {
+		return func() Snapshot {
+			s := CurrentSnapshot()
+			s.fma = fma
+			return s
+		}
+	}
+
+	checkSnapshotBrackets(t, "16B", snap(false), unixNanoARM16B)
+	checkSnapshotBrackets(t, "FMADD", snap(true), unixNanoARMFMADD)
+	checkSnapshotBrackets(t, "fence", snap(false), unixNanoARM16Bfence)
+
+	if hasECV {
+		checkSnapshotBrackets(t, "ECV", snap(false), unixNanoARM16BECV)
+	}
+}
@@ -4,19 +4,11 @@ package tsc
 
 func reset() bool { return false }
 
-// Calibrate calibrates tsc & wall clock.
-//
-// It's a good practice that run Calibrate period (every hour) outside,
-// because the wall clock may be calibrated (e.g. NTP).
-//
-// If !enabled do nothing.
-func Calibrate() {
-	return
-}
+func hasPerNodeFastPath() bool { return false }
 
-func CalibrateWithCoeff(coeff float64) {
-	return
-}
+func isHardwareSupported() bool { return false }
+
+func nominalFrequency() float64 { return 0 }
 
 // GetInOrder gets tsc value in strictly order.
 // It's used for helping calibrate to avoid out-of-order issues.
@@ -33,3 +25,7 @@ func RDTSC() int64 {
 func LoadOffsetCoeff(src *byte) (offset int64, coeff float64) {
 	return 0, 0
 }
+
+func storeOffsetCoeff(dst *byte, offset int64, coeff float64) {}
+
+func storeOffsetFCoeff(dst *byte, offset, coeff float64) {}
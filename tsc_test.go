@@ -3,6 +3,9 @@ package tsc
 import (
 	"context"
 	"math"
+	"math/rand"
+	"runtime"
+	"slices"
 	"testing"
 	"time"
 )
@@ -33,6 +36,62 @@ func BenchmarkUnixNano(b *testing.B) {
 	}
 }
 
+func TestUnixNanoCPU(t *testing.T) {
+	t.Parallel()
+
+	for range 1024 {
+		exp := time.Now().UnixNano()
+		ns, cpu, node := UnixNanoCPU()
+
+		if math.Abs(float64(ns-exp)) > float64(time.Millisecond) {
+			t.Fatalf("timestamp too far away from system clock, exp: %d, got: %d", exp, ns)
+		}
+
+		if runtime.GOOS != "linux" {
+			continue
+		}
+
+		if cpu < 0 || node < 0 {
+			t.Fatalf("failed to get cpu & node: %d, %d", cpu, node)
+		}
+	}
+}
+
+func BenchmarkUnixNanoCPU(b *testing.B) {
+	for range b.N {
+		_, _, _ = UnixNanoCPU()
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		list string
+		exp  []int
+	}{
+		{"", nil},
+		{"0\n", []int{0}},
+		{"0-3", []int{0, 1, 2, 3}},
+		{"0-1,8,10-11\n", []int{0, 1, 8, 10, 11}},
+	}
+
+	for _, c := range cases {
+		got, err := parseCPUList(c.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(got, c.exp) {
+			t.Fatalf("cpulist %q mismatch, exp: %v, got: %v", c.list, c.exp, got)
+		}
+	}
+
+	if _, err := parseCPUList("0-x"); err == nil {
+		t.Fatal("should fail on invalid cpulist")
+	}
+}
+
 func BenchmarkSysTime(b *testing.B) {
 	for range b.N {
 		_ = time.Now().UnixNano()
@@ -110,3 +169,33 @@ func TestCalibrate(t *testing.T) {
 	time.Sleep(3 * time.Second)
 	cancel()
 }
+
+// BenchmarkUnixNanoPointerChase mixes UnixNano with pointer chasing over a big shuffled list,
+// which evicts the cache lines used by UnixNano, for showing the cost of dependent loads in the fast path.
+func BenchmarkUnixNanoPointerChase(b *testing.B) {
+	const n = 1 << 18 // 16MB, bigger than most L2 caches.
+
+	type node struct {
+		next *node
+		_    [CacheLineSize - 8]byte
+	}
+
+	nodes := make([]node, n)
+	perm := rand.Perm(n)
+
+	for i := range perm {
+		nodes[perm[i]].next = &nodes[perm[(i+1)%n]]
+	}
+
+	p := &nodes[0]
+
+	b.ResetTimer()
+
+	for range b.N {
+		for range 4 {
+			p = p.next
+		}
+
+		_ = UnixNano()
+	}
+}
@@ -0,0 +1,59 @@
+// Package tsctime provides a subset of the standard time package backed by tsc.UnixNano,
+// so hot paths could switch to tsc by changing the import only.
+//
+// Times returned by Now carry no monotonic clock reading, so Sub, Before, After, Equal & Compare
+// use the wall clock reading, which is tsc.UnixNano. Any two Times got by Now are comparable,
+// but their difference includes offset steps made by calibrations in between (usually within µs),
+// use tsc.NowTicks & tsc.Since for measuring durations if that matters.
+package tsctime
+
+import (
+	"time"
+
+	"github.com/templexxx/tsc"
+)
+
+// Now returns the current local time by tsc.UnixNano.
+func Now() time.Time {
+	return time.Unix(0, tsc.UnixNano())
+}
+
+// Since returns the time elapsed since t, it's shorthand for Now().Sub(t).
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Until returns the duration until t, it's shorthand for t.Sub(Now()).
+func Until(t time.Time) time.Duration {
+	return t.Sub(Now())
+}
+
+// Unix returns the current Unix time in seconds, it's Now().Unix() without building a Time.
+func Unix() int64 {
+	return floorDiv(tsc.UnixNano(), int64(time.Second))
+}
+
+// UnixMilli returns the current Unix time in milliseconds, it's Now().UnixMilli() without building a Time.
+func UnixMilli() int64 {
+	return floorDiv(tsc.UnixNano(), int64(time.Millisecond))
+}
+
+// UnixMicro returns the current Unix time in microseconds, it's Now().UnixMicro() without building a Time.
+func UnixMicro() int64 {
+	return floorDiv(tsc.UnixNano(), int64(time.Microsecond))
+}
+
+// UnixNano returns the current Unix time in nanoseconds, it's tsc.UnixNano.
+func UnixNano() int64 {
+	return tsc.UnixNano()
+}
+
+// floorDiv returns a / b rounded toward negative infinity as time.Time.Unix does (b > 0).
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b < 0 {
+		q--
+	}
+
+	return q
+}
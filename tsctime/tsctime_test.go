@@ -0,0 +1,103 @@
+package tsctime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNow(t *testing.T) {
+	t.Parallel()
+
+	// Calibration error & out-of-order reading.
+	const tolerance = 100 * time.Microsecond
+
+	for range 1000 {
+		t0 := time.Now()
+		now := Now()
+		t1 := time.Now()
+
+		if now.Before(t0.Add(-tolerance)) || now.After(t1.Add(tolerance)) {
+			t.Fatalf("now mismatch: %s not in [%s, %s]", now, t0, t1)
+		}
+
+		if now.Location() != time.Local {
+			t.Fatalf("location mismatch: exp: Local, got: %s", now.Location())
+		}
+	}
+}
+
+func TestSubAndCompare(t *testing.T) {
+	t.Parallel()
+
+	start := Now()
+
+	time.Sleep(10 * time.Millisecond)
+
+	end := Now()
+
+	if !end.After(start) || !start.Before(end) || end.Compare(start) != 1 || end.Equal(start) {
+		t.Fatalf("comparison mismatch: start: %s, end: %s", start, end)
+	}
+
+	if d := end.Sub(start); d < 10*time.Millisecond || d > time.Second {
+		t.Fatalf("sub mismatch: %s", d)
+	}
+
+	if d := Since(start); d < 10*time.Millisecond || d > time.Second {
+		t.Fatalf("since mismatch: %s", d)
+	}
+
+	if d := Until(start.Add(time.Hour)); d > time.Hour || d < time.Hour-time.Second {
+		t.Fatalf("until mismatch: %s", d)
+	}
+
+	// Round trip through serialized forms keeps equality (no monotonic reading).
+	if ts := time.Unix(0, start.UnixNano()); !ts.Equal(start) || ts != start {
+		t.Fatalf("round trip mismatch: exp: %s, got: %s", start, ts)
+	}
+}
+
+func TestUnix(t *testing.T) {
+	t.Parallel()
+
+	ns := UnixNano()
+	now := time.Unix(0, ns)
+
+	// Each is read after ns, so it's not less than now's in the same unit (within tolerance).
+	cases := []struct {
+		name string
+		got  int64
+		exp  int64
+		unit int64
+	}{
+		{"sec", Unix(), now.Unix(), int64(time.Second)},
+		{"milli", UnixMilli(), now.UnixMilli(), int64(time.Millisecond)},
+		{"micro", UnixMicro(), now.UnixMicro(), int64(time.Microsecond)},
+		{"nano", UnixNano(), now.UnixNano(), 1},
+	}
+
+	for _, c := range cases {
+		if d := (c.got - c.exp) * c.unit; d < -int64(time.Millisecond) || d > int64(time.Second) {
+			t.Fatalf("%s mismatch: exp: %d, got: %d", c.name, c.exp, c.got)
+		}
+	}
+
+	for _, ns := range []int64{-1, -1_000_000_001, 0, 1_999_999_999} {
+		ts := time.Unix(0, ns)
+		if got := floorDiv(ns, int64(time.Second)); got != ts.Unix() {
+			t.Fatalf("floor div mismatch at %d: exp: %d, got: %d", ns, ts.Unix(), got)
+		}
+	}
+}
+
+func BenchmarkNow(b *testing.B) {
+	for range b.N {
+		_ = Now()
+	}
+}
+
+func BenchmarkTimeNow(b *testing.B) {
+	for range b.N {
+		_ = time.Now()
+	}
+}